@@ -0,0 +1,238 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// loadAdminIDs parses ADMIN_USER_IDS (comma-separated Telegram user IDs of bot operators)
+func loadAdminIDs() map[int64]bool {
+	admins := make(map[int64]bool)
+	for _, part := range strings.Split(os.Getenv("ADMIN_USER_IDS"), ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		userID, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			log.Printf("⚠️ Invalid user ID '%s' in ADMIN_USER_IDS, skipping", part)
+			continue
+		}
+		admins[userID] = true
+	}
+	return admins
+}
+
+// isAdmin reports whether the user may run operator commands
+func (tb *TelegramBot) isAdmin(userID int64) bool {
+	return tb.adminIDs[userID]
+}
+
+// requireAdmin replies with a refusal for non-admins and reports whether to continue
+func (tb *TelegramBot) requireAdmin(chatID int64, userID int64) bool {
+	if tb.isAdmin(userID) {
+		return true
+	}
+	tb.sendMessage(chatID, "⛔ Bu komut sadece bot yöneticileri içindir.")
+	return false
+}
+
+// notifyAdmins sends an operator alert to every admin
+func (tb *TelegramBot) notifyAdmins(text string) {
+	for adminID := range tb.adminIDs {
+		tb.sendMessage(adminID, text)
+	}
+}
+
+// handleRateCommand shows the Upbit polling rate controller state
+func (tb *TelegramBot) handleRateCommand(chatID int64, userID int64) {
+	if !tb.requireAdmin(chatID, userID) {
+		return
+	}
+	if tb.upbitMonitor == nil {
+		tb.sendMessage(chatID, "❌ Upbit monitor çalışmıyor.")
+		return
+	}
+
+	stats := tb.upbitMonitor.RateStats()
+	formatAgo := func(t time.Time) string {
+		if t.IsZero() {
+			return "hiç"
+		}
+		return time.Since(t).Round(time.Second).String() + " önce"
+	}
+
+	rateLimitedPct := 0.0
+	if stats.Requests > 0 {
+		rateLimitedPct = float64(stats.RateLimited) / float64(stats.Requests) * 100
+	}
+
+	tb.sendMessage(chatID, fmt.Sprintf(`⏱️ UPBIT İSTEK HIZI
+
+• Hedef: %.2f istek/sn
+• Gerçekleşen (son 1 dk): %.2f istek/sn
+• Sınırlar: %.2f - %.2f istek/sn (+%.2f / %v)
+• Sağlıklı proxy: %d (her biri %v dinlenir)
+• 429 oranı: %.1f%% (%d/%d)
+• Yarıya indirme: %d kez, son: %s
+• Son artış: %s
+
+Hız arttıkça tespit hızlanır ama 429/ban riski artar.`,
+		stats.TargetRate, stats.EffectiveRate,
+		stats.MinRate, stats.MaxRate, stats.Step, rateIncreaseInterval,
+		stats.HealthyProxies, stats.ProxyInterval.Round(time.Millisecond),
+		rateLimitedPct, stats.RateLimited, stats.Requests,
+		stats.Decreases, formatAgo(stats.LastDecrease),
+		formatAgo(stats.LastIncrease)))
+}
+
+// handleReloadProxiesCommand re-reads the proxy list without restarting the monitor
+func (tb *TelegramBot) handleReloadProxiesCommand(chatID int64, userID int64) {
+	if !tb.requireAdmin(chatID, userID) {
+		return
+	}
+	if tb.upbitMonitor == nil {
+		tb.sendMessage(chatID, "❌ Upbit monitor çalışmıyor.")
+		return
+	}
+
+	result, err := tb.upbitMonitor.ReloadProxies()
+	if err != nil {
+		tb.sendMessage(chatID, fmt.Sprintf("❌ Proxy listesi yüklenemedi: %v", err))
+		return
+	}
+
+	orNone := func(names []string) string {
+		if len(names) == 0 {
+			return "-"
+		}
+		return strings.Join(names, ", ")
+	}
+	tb.sendMessage(chatID, fmt.Sprintf(`🔁 PROXY LİSTESİ YENİLENDİ
+
+• Kaynak: %s
+• Toplam: %d
+• Eklenen: %s
+• Çıkarılan: %s
+• Güncellenen: %s
+
+Değişmeyen proxy'lerin bağlantı, ETag ve cooldown durumu korundu.`,
+		result.Source, result.Total, orNone(result.Added), orNone(result.Removed), orNone(result.Updated)))
+}
+
+// handleProxyReportCommand shows which proxies see new announcements first: /proxyreport [days]
+func (tb *TelegramBot) handleProxyReportCommand(chatID int64, userID int64, args string) {
+	if !tb.requireAdmin(chatID, userID) {
+		return
+	}
+	if tb.upbitMonitor == nil {
+		tb.sendMessage(chatID, "❌ Upbit monitor çalışmıyor.")
+		return
+	}
+
+	days := 7
+	if args = strings.TrimSpace(args); args != "" {
+		parsed, err := strconv.Atoi(args)
+		if err != nil || parsed < 1 {
+			tb.sendMessage(chatID, "❌ Kullanım: /proxyreport [gün], örn. /proxyreport 30")
+			return
+		}
+		days = parsed
+	}
+
+	report, err := tb.upbitMonitor.ProxyRaceReport(time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		tb.sendMessage(chatID, fmt.Sprintf("❌ ETag kayıtları okunamadı: %v", err))
+		return
+	}
+	if report.Races == 0 {
+		tb.sendMessage(chatID, fmt.Sprintf("ℹ️ Son %d günde kayıtlı ETag yarışı yok.", days))
+		return
+	}
+
+	formatMs := func(d time.Duration) string {
+		if d == 0 {
+			return "-"
+		}
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "🏁 PROXY YARIŞ RAPORU (son %d gün)\n\n", days)
+	fmt.Fprintf(&b, "• Yarış (yeni ETag): %d\n• Gözlem: %d\n\n", report.Races, report.Entries)
+	const maxRows = 20
+	for i, s := range report.Proxies {
+		if i == maxRows {
+			fmt.Fprintf(&b, "… ve %d proxy daha\n", len(report.Proxies)-maxRows)
+			break
+		}
+		fmt.Fprintf(&b, "%d. %s\n   🥇 %d/%d (%%%.0f) • gecikme medyan %s, p90 %s • Date→tespit %s\n",
+			i+1, s.Name, s.Wins, report.Races, s.WinRate*100,
+			formatMs(s.MedianLag), formatMs(s.P90Lag), formatMs(s.MedianDateToSeen))
+	}
+	b.WriteString("\nGecikme: kazanan proxy'nin gördüğü ana göre. Date→tespit: Upbit'in Date başlığından (saniye hassasiyetinde) yanıtı almamıza kadar.")
+	tb.sendMessage(chatID, b.String())
+}
+
+// handleHealthCommand shows the detection loop's self-diagnosis and watchdog thresholds
+func (tb *TelegramBot) handleHealthCommand(chatID int64, userID int64) {
+	if !tb.requireAdmin(chatID, userID) {
+		return
+	}
+	if tb.upbitMonitor == nil {
+		tb.sendMessage(chatID, "❌ Upbit monitor çalışmıyor.")
+		return
+	}
+
+	h := tb.upbitMonitor.Health()
+	status := "🟢 Çalışıyor"
+	switch {
+	case !h.Running:
+		status = "⚪ Başlatılmadı"
+	case h.Paused:
+		status = "⏸️ Sessiz saatler (duraklatıldı)"
+	case detectionWatchdog != nil && h.SinceHealthy > detectionWatchdog.stale:
+		status = "🔴 Başarılı istek yok"
+	}
+
+	lastPoll := "hiç"
+	if !h.LastHealthyPoll.IsZero() {
+		lastPoll = fmt.Sprintf("%v önce (%s)", time.Since(h.LastHealthyPoll).Round(time.Millisecond), h.LastHealthyProxy)
+	}
+	parseLine := fmt.Sprintf("%d art arda", h.ParseFailures)
+	if h.LastParseError != "" {
+		parseLine += fmt.Sprintf(" (son hata: %s)", h.LastParseError)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "🩺 TESPİT SAĞLIĞI\n\n")
+	fmt.Fprintf(&b, "• Durum: %s\n", status)
+	fmt.Fprintf(&b, "• Mod: %s\n", h.PollMode)
+	fmt.Fprintf(&b, "• Son başarılı istek: %s\n", lastPoll)
+	fmt.Fprintf(&b, "• Proxy: %d toplam, %d karantinada (%%%.0f), %d dinleniyor\n", h.Proxies, h.Quarantined, h.QuarantinedPct(), h.Resting)
+	fmt.Fprintf(&b, "• Ayrıştırma hatası: %s\n", parseLine)
+	fmt.Fprintf(&b, "• Hız: %.2f hedef / %.2f gerçekleşen istek/sn\n", h.Rate.TargetRate, h.Rate.EffectiveRate)
+	if pipelineCanary != nil {
+		c := pipelineCanary.Status()
+		switch {
+		case c.Runs == 0:
+			fmt.Fprintf(&b, "• Kanarya: henüz çalışmadı\n")
+		case c.Failed:
+			fmt.Fprintf(&b, "• Kanarya: ❌ %s\n", c.LastError)
+		default:
+			fmt.Fprintf(&b, "• Kanarya: ✅ %v (medyan %v, %d çalışma)\n", c.LastLatency.Round(time.Microsecond), c.MedianLatency.Round(time.Microsecond), c.Runs)
+		}
+	}
+	if clockSync != nil {
+		upbit, bitget := clockSync.Estimates()
+		fmt.Fprintf(&b, "• Saat sapması: Upbit %v, Bitget %v\n", upbit.Offset.Round(time.Millisecond), bitget.Offset.Round(time.Millisecond))
+	}
+	if w := detectionWatchdog; w != nil {
+		fmt.Fprintf(&b, "\nUyarı eşikleri: %v başarısız, %%%.0f karantina, %d ayrıştırma hatası", w.stale, w.quarantinePct, w.parseFailures)
+	}
+	tb.sendMessage(chatID, b.String())
+}
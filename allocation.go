@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Margin allocation policies for notices that list several tickers at once
+const (
+	AllocationSplit = "split" // User margin divided evenly (default)
+	AllocationFull  = "full"  // Full user margin on every ticker
+	AllocationFirst = "first" // Only the first tradable ticker
+	AllocationCap   = "cap"   // Full margin each, total capped at AllocationCapUSDT
+)
+
+// TradeAllocation is the margin one ticker of a notice gets for one user (0 = not traded)
+type TradeAllocation struct {
+	Listing    ListingInfo
+	MarginUSDT float64
+}
+
+// allocationPolicy returns the user's policy, defaulting to split
+func (u *UserData) allocationPolicy() string {
+	if u.AllocationPolicy == "" {
+		return AllocationSplit
+	}
+	return u.AllocationPolicy
+}
+
+// allocationPlan spreads the user's margin over the tickers of one notice they will trade
+func (u *UserData) allocationPlan(listings []ListingInfo) []TradeAllocation {
+	plan := make([]TradeAllocation, len(listings))
+	n := float64(len(listings))
+	for i, listing := range listings {
+		plan[i].Listing = listing
+
+		switch u.allocationPolicy() {
+		case AllocationFull:
+			plan[i].MarginUSDT = u.MarginUSDT
+		case AllocationFirst:
+			if i == 0 {
+				plan[i].MarginUSDT = u.MarginUSDT
+			}
+		case AllocationCap:
+			perTicker := u.MarginUSDT
+			if u.AllocationCapUSDT > 0 {
+				perTicker = min(perTicker, u.AllocationCapUSDT/n)
+			}
+			plan[i].MarginUSDT = perTicker
+		default:
+			plan[i].MarginUSDT = u.MarginUSDT / n
+		}
+	}
+	return plan
+}
+
+// formatAllocationPolicy renders the policy for the filters screen
+func (u *UserData) formatAllocationPolicy() string {
+	switch u.allocationPolicy() {
+	case AllocationFull:
+		return "her coine tam marjin"
+	case AllocationFirst:
+		return "sadece ilk coin"
+	case AllocationCap:
+		return fmt.Sprintf("her coine tam marjin, toplam en fazla %.2f USDT", u.AllocationCapUSDT)
+	default:
+		return "marjini eşit böl"
+	}
+}
+
+// formatAllocationPlan renders a plan for trigger and approval messages
+func formatAllocationPlan(user *UserData, plan []TradeAllocation) string {
+	var b strings.Builder
+	total := 0.0
+	fmt.Fprintf(&b, "📋 Plan (%s):", user.formatAllocationPolicy())
+	for _, a := range plan {
+		if a.MarginUSDT <= 0 {
+			fmt.Fprintf(&b, "\n• %s: atlandı", a.Listing.Symbol)
+			continue
+		}
+		total += a.MarginUSDT
+		fmt.Fprintf(&b, "\n• %s: %.2f USDT x%d", a.Listing.Symbol, a.MarginUSDT, user.Leverage)
+		if a.Listing.TradingSymbol != a.Listing.Symbol+"USDT" {
+			fmt.Fprintf(&b, " (%s)", a.Listing.TradingSymbol)
+		}
+		if a.Listing.Unverified != "" {
+			fmt.Fprintf(&b, " ⚠️ %s", a.Listing.Unverified)
+		}
+	}
+	fmt.Fprintf(&b, "\nToplam marjin: %.2f USDT", total)
+	return b.String()
+}
+
+// parseAllocationArgs parses "/allocation split|full|first|cap <usdt>"
+func parseAllocationArgs(args string) (policy string, capUSDT float64, err error) {
+	fields := strings.Fields(strings.ToLower(args))
+	if len(fields) == 0 {
+		return "", 0, fmt.Errorf("politika eksik")
+	}
+	switch fields[0] {
+	case AllocationSplit, AllocationFull, AllocationFirst:
+		return fields[0], 0, nil
+	case AllocationCap:
+		if len(fields) != 2 {
+			return "", 0, fmt.Errorf("toplam limit eksik")
+		}
+		capUSDT, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil || capUSDT <= 0 {
+			return "", 0, fmt.Errorf("geçersiz limit")
+		}
+		return AllocationCap, capUSDT, nil
+	}
+	return "", 0, fmt.Errorf("bilinmeyen politika %q", fields[0])
+}
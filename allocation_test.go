@@ -0,0 +1,66 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAllocationPlan(t *testing.T) {
+	three := []ListingInfo{{Symbol: "AAA"}, {Symbol: "BBB"}, {Symbol: "CCC"}}
+
+	tests := []struct {
+		name     string
+		user     UserData
+		listings []ListingInfo
+		want     []float64
+	}{
+		{"default splits evenly", UserData{MarginUSDT: 90}, three, []float64{30, 30, 30}},
+		{"split single ticker", UserData{MarginUSDT: 90, AllocationPolicy: AllocationSplit}, three[:1], []float64{90}},
+		{"full on every ticker", UserData{MarginUSDT: 50, AllocationPolicy: AllocationFull}, three, []float64{50, 50, 50}},
+		{"first only", UserData{MarginUSDT: 50, AllocationPolicy: AllocationFirst}, three, []float64{50, 0, 0}},
+		{"cap below full margin", UserData{MarginUSDT: 50, AllocationPolicy: AllocationCap, AllocationCapUSDT: 60}, three, []float64{20, 20, 20}},
+		{"cap above full margin", UserData{MarginUSDT: 50, AllocationPolicy: AllocationCap, AllocationCapUSDT: 1000}, three, []float64{50, 50, 50}},
+		{"cap without limit is full", UserData{MarginUSDT: 50, AllocationPolicy: AllocationCap}, three[:2], []float64{50, 50}},
+		{"no listings", UserData{MarginUSDT: 50}, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := tt.user.allocationPlan(tt.listings)
+			if len(plan) != len(tt.want) {
+				t.Fatalf("plan has %d entries, want %d", len(plan), len(tt.want))
+			}
+			for i, a := range plan {
+				if a.Listing.Symbol != tt.listings[i].Symbol {
+					t.Errorf("entry %d is %s, want %s", i, a.Listing.Symbol, tt.listings[i].Symbol)
+				}
+				if math.Abs(a.MarginUSDT-tt.want[i]) > 1e-9 {
+					t.Errorf("%s margin = %v, want %v", a.Listing.Symbol, a.MarginUSDT, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseAllocationArgs(t *testing.T) {
+	tests := []struct {
+		args    string
+		policy  string
+		capUSDT float64
+		wantErr bool
+	}{
+		{"split", AllocationSplit, 0, false},
+		{"FULL", AllocationFull, 0, false},
+		{"first", AllocationFirst, 0, false},
+		{"cap 150", AllocationCap, 150, false},
+		{"cap", "", 0, true},
+		{"cap -5", "", 0, true},
+		{"", "", 0, true},
+		{"random", "", 0, true},
+	}
+	for _, tt := range tests {
+		policy, capUSDT, err := parseAllocationArgs(tt.args)
+		if (err != nil) != tt.wantErr || policy != tt.policy || capUSDT != tt.capUSDT {
+			t.Errorf("parseAllocationArgs(%q) = %q, %v, %v", tt.args, policy, capUSDT, err)
+		}
+	}
+}
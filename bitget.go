@@ -5,15 +5,20 @@ import (
         "crypto/hmac"
         "crypto/sha256"
         "encoding/base64"
+        "errors"
         json "github.com/json-iterator/go"
         "fmt"
         "io"
+        "math"
         "net/http"
         "net/url"
+        "sort"
         "strconv"
         "strings"
         "sync"
         "time"
+
+        "upbit-bitget-bot/internal/timesync"
 )
 
 type BalanceCache struct {
@@ -31,6 +36,7 @@ type BitgetAPI struct {
         BaseURL    string
         Client     *http.Client
         Cache      *BalanceCache
+        budget     *RateBudget // Shared by every client using this API key
 }
 
 type OrderSide string
@@ -92,6 +98,62 @@ type OrderResponse struct {
         Size       float64 `json:"-"`
         MarginUSDT float64 `json:"-"`
         Leverage   int     `json:"-"`
+        // Entry execution (OpenPrice/Size are the actual average fill and filled size when known)
+        EntryMode     string  `json:"-"`
+        QuotePrice    float64 `json:"-"` // Last price the order was sized from
+        RequestedSize float64 `json:"-"`
+        UnfilledSize  float64 `json:"-"`
+        Attempts      int     `json:"-"`
+        FeeUSDT       float64   `json:"-"` // Entry fees charged on the fills
+        FilledAt      time.Time `json:"-"` // Last fill update reported by Bitget
+        FillConfirmed bool      `json:"-"` // OpenPrice/Size/FeeUSDT come from Bitget order detail
+        ChildOrderIDs []string  `json:"-"` // TWAP slices that filled
+        Slices        int       `json:"-"` // TWAP slices planned
+        AbortReason   string    `json:"-"` // Why a TWAP entry stopped early
+}
+
+// OrderDetail is an order's state and fills from /api/v2/mix/order/detail
+type OrderDetail struct {
+        OrderID    string `json:"orderId"`
+        Symbol     string `json:"symbol"`
+        Size       string `json:"size"`
+        BaseVolume string `json:"baseVolume"` // Filled size
+        PriceAvg   string `json:"priceAvg"`
+        Fee        string `json:"fee"` // Negative when charged
+        State      string `json:"state"` // live, partially_filled, filled, canceled
+        UpdatedAt  string `json:"uTime"`
+}
+
+// Filled returns the filled size in coins
+func (d OrderDetail) Filled() float64 {
+        filled, _ := strconv.ParseFloat(d.BaseVolume, 64)
+        return filled
+}
+
+// AvgPrice returns the average fill price, 0 if nothing filled
+func (d OrderDetail) AvgPrice() float64 {
+        avg, _ := strconv.ParseFloat(d.PriceAvg, 64)
+        return avg
+}
+
+// FeeUSDT returns the fees charged on the fills
+func (d OrderDetail) FeeUSDT() float64 {
+        fee, _ := strconv.ParseFloat(d.Fee, 64)
+        return math.Abs(fee)
+}
+
+// FilledAt returns when Bitget last updated the order, zero if unknown
+func (d OrderDetail) FilledAt() time.Time {
+        ms, err := strconv.ParseInt(d.UpdatedAt, 10, 64)
+        if err != nil || ms <= 0 {
+                return time.Time{}
+        }
+        return time.UnixMilli(ms)
+}
+
+// Final reports whether the order can no longer fill
+func (d OrderDetail) Final() bool {
+        return d.State == "filled" || d.State == "canceled" || d.State == "cancelled"
 }
 
 type APIResponse struct {
@@ -113,21 +175,19 @@ type AccountBalance struct {
         BonusAmount       string `json:"bonusAmount"`
 }
 
-type ServerTimeResponse struct {
-        Code        string `json:"code"`
-        Msg         string `json:"msg"`
-        RequestTime int64  `json:"requestTime"`
-        Data        struct {
-                ServerTime string `json:"serverTime"`
-        } `json:"data"`
+type ContractInfo struct {
+        Symbol       string `json:"symbol"`
+        BaseCoin     string `json:"baseCoin"`
+        QuoteCoin    string `json:"quoteCoin"`
+        SymbolStatus string `json:"symbolStatus"`
+        LaunchTime   string `json:"launchTime"`
+        PricePlace   string `json:"pricePlace"`  // Price decimals
+        VolumePlace  string `json:"volumePlace"` // Size decimals
+        MinTradeNum  string `json:"minTradeNum"` // Smallest order size in coins
 }
 
-type TimeSyncResult struct {
-        ServerTime    time.Time
-        LocalTime     time.Time
-        ClockOffset   time.Duration
-        NetworkLatency time.Duration
-}
+// TimeSyncResult is one clock measurement (see internal/timesync)
+type TimeSyncResult = timesync.Sample
 
 func NewBitgetAPI(apiKey, apiSecret, passphrase string) *BitgetAPI {
         api := &BitgetAPI{
@@ -138,6 +198,7 @@ func NewBitgetAPI(apiKey, apiSecret, passphrase string) *BitgetAPI {
                 Client: &http.Client{
                         Timeout: 30 * time.Second,
                 },
+                budget: bitgetBudgetFor(apiKey),
         }
 
         api.Cache = &BalanceCache{
@@ -171,7 +232,8 @@ func (b *BitgetAPI) makeRequestWithRetry(method, endpoint string, queryParams ma
                 }
         }
 
-        timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+        b.budget.Wait()
+        timestamp := strconv.FormatInt(bitgetNow().UnixMilli(), 10) // Bitget's clock, not ours
         requestPath := endpoint
         if len(queryParams) > 0 {
                 params := make([]string, 0, len(queryParams))
@@ -253,6 +315,60 @@ func (b *BitgetAPI) PlaceOrder(symbol string, side OrderSide, size float64, trad
         return &orderResp, nil
 }
 
+// PlaceLimitOrder places an opening limit order with a time-in-force of "ioc", "fok" or "gtc";
+// size and price must already be rounded to the contract's precision
+func (b *BitgetAPI) PlaceLimitOrder(symbol string, side OrderSide, size, price, force string) (*OrderResponse, error) {
+        orderReq := OrderRequest{
+                Symbol:      symbol,
+                ProductType: "USDT-FUTURES",
+                MarginMode:  "isolated",
+                MarginCoin:  "USDT",
+                Size:        size,
+                Side:        side,
+                TradeSide:   "open",
+                OrderType:   OrderTypeLimit,
+                Price:       price,
+                Force:       force,
+        }
+
+        var orderResp OrderResponse
+        if err := b.makeRequest("POST", "/api/v2/mix/order/place-order", orderReq, &orderResp); err != nil {
+                return nil, fmt.Errorf("failed to place limit order: %w", err)
+        }
+        return &orderResp, nil
+}
+
+// CancelOrder cancels a live order; Bitget rejects it once the order is filled or cancelled
+func (b *BitgetAPI) CancelOrder(symbol, orderID string) error {
+        cancelReq := map[string]string{
+                "symbol":      symbol,
+                "productType": "USDT-FUTURES",
+                "marginCoin":  "USDT",
+                "orderId":     orderID,
+        }
+
+        var result OrderResponse
+        if err := b.makeRequest("POST", "/api/v2/mix/order/cancel-order", cancelReq, &result); err != nil {
+                return fmt.Errorf("failed to cancel order %s: %w", orderID, err)
+        }
+        return nil
+}
+
+// GetOrderDetail returns the state and fills of one order
+func (b *BitgetAPI) GetOrderDetail(symbol, orderID string) (*OrderDetail, error) {
+        queryParams := map[string]string{
+                "symbol":      symbol,
+                "productType": "USDT-FUTURES",
+                "orderId":     orderID,
+        }
+
+        var detail OrderDetail
+        if err := b.makeRequestWithRetry("GET", "/api/v2/mix/order/detail", queryParams, nil, &detail); err != nil {
+                return nil, err
+        }
+        return &detail, nil
+}
+
 func (b *BitgetAPI) SetLeverage(symbol string, leverage int) error {
         endpoint := "/api/v2/mix/account/set-leverage"
         leverageReq := map[string]interface{}{
@@ -291,7 +407,8 @@ func (b *BitgetAPI) GetSymbolPrice(symbol string) (float64, error) {
         }
         
         // Set headers
-        timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+        b.budget.Wait()
+        timestamp := strconv.FormatInt(bitgetNow().UnixMilli(), 10) // Bitget's clock, not ours
         signaturePath := endpoint + "?" + queryString
         
         req.Header.Set("ACCESS-KEY", b.APIKey)
@@ -429,9 +546,9 @@ func (bc *BalanceCache) RefreshBalance() error {
         return fmt.Errorf("USDT account not found")
 }
 
-func (b *BitgetAPI) OpenLongPosition(symbol string, marginUSDT float64, leverage int) (*OrderResponse, error) {
-        fmt.Printf("🚀 Starting position: symbol=%s, user_margin=%.2f USDT, requested_leverage=%dx\n",
-                symbol, marginUSDT, leverage)
+func (b *BitgetAPI) OpenLongPosition(symbol string, marginUSDT float64, leverage int, entry EntryParams) (*OrderResponse, error) {
+        fmt.Printf("🚀 Starting position: symbol=%s, user_margin=%.2f USDT, requested_leverage=%dx, entry=%s\n",
+                symbol, marginUSDT, leverage, entry.Mode)
 
         originalMargin := marginUSDT
         originalLeverage := leverage
@@ -494,6 +611,13 @@ func (b *BitgetAPI) OpenLongPosition(symbol string, marginUSDT float64, leverage
         positionSizeUSDT := marginUSDT * float64(leverage)
         baseSize := positionSizeUSDT / currentPrice
 
+        switch entry.Mode {
+        case EntryIOC, EntryFOK:
+                return b.openLongLimit(symbol, currentPrice, baseSize, originalMargin, originalLeverage, entry)
+        case EntryTWAP:
+                return b.openLongTWAP(symbol, currentPrice, baseSize, originalMargin, originalLeverage, entry)
+        }
+
         fmt.Printf("📊 Position calculation: margin=%.2f USDT, leverage=%dx, position_size=%.2f USDT, price=%.6f, coin_amount=%.8f\n",
                 marginUSDT, leverage, positionSizeUSDT, currentPrice, baseSize)
 
@@ -509,10 +633,37 @@ func (b *BitgetAPI) OpenLongPosition(symbol string, marginUSDT float64, leverage
                 orderResp.Size = baseSize
                 orderResp.MarginUSDT = originalMargin
                 orderResp.Leverage = originalLeverage
+                orderResp.EntryMode = EntryMarket
+                orderResp.QuotePrice = currentPrice
+                orderResp.RequestedSize = baseSize
+                orderResp.Attempts = 1
+
+                // One read replaces the quote with the real average fill when the order is already
+                // final; otherwise the position is confirmed in the background (confirmFill).
+                // A market order is never waited on or cancelled here.
+                detail, err := b.GetOrderDetail(symbol, orderResp.OrderID)
+                if err != nil {
+                        fmt.Printf("⏳ Fill of %s order %s unreadable, showing the quote: %v\n", symbol, orderResp.OrderID, err)
+                } else if !detail.Final() {
+                        fmt.Printf("⏳ Fill of %s order %s not final yet (%s, %.8g filled), showing the quote\n", symbol, orderResp.OrderID, detail.State, detail.Filled())
+                } else if detail.Filled() == 0 {
+                        return nil, fmt.Errorf("market order %s %s with nothing filled", orderResp.OrderID, detail.State)
+                } else if detail.AvgPrice() > 0 {
+                        orderResp.OpenPrice = detail.AvgPrice()
+                        orderResp.Size = detail.Filled()
+                        orderResp.FeeUSDT = detail.FeeUSDT()
+                        orderResp.FilledAt = detail.FilledAt()
+                        orderResp.FillConfirmed = true
+                        if detail.State != "filled" {
+                                orderResp.UnfilledSize = max(0, baseSize-detail.Filled())
+                        }
+                        fmt.Printf("🧾 Fill of %s order %s: %s %.8g @ %.8g, fee %.4f USDT\n",
+                                symbol, orderResp.OrderID, detail.State, detail.Filled(), detail.AvgPrice(), detail.FeeUSDT())
+                }
 
                 fmt.Printf("✅ Position opened successfully!\n")
                 fmt.Printf("🏷️ Details: Symbol=%s, Size=%.8f, OpenPrice=%.4f, UserMargin=%.2f, UserLeverage=%dx (Actual=%dx)\n",
-                        symbol, baseSize, currentPrice, originalMargin, originalLeverage, leverage)
+                        symbol, orderResp.Size, orderResp.OpenPrice, originalMargin, originalLeverage, leverage)
         }
 
         return orderResp, nil
@@ -613,55 +764,173 @@ func (b *BitgetAPI) CloseAllPositions() (*OrderResponse, error) {
         return &OrderResponse{OrderID: "all_closed"}, nil
 }
 
-// GetServerTime retrieves Bitget server timestamp for time synchronization
-func (b *BitgetAPI) GetServerTime() (*TimeSyncResult, error) {
-        localTimeBefore := time.Now()
-        
-        url := b.BaseURL + "/api/v2/public/time"
-        req, err := http.NewRequest("GET", url, nil)
-        if err != nil {
-                return nil, fmt.Errorf("failed to create request: %w", err)
+// getPublic performs an unsigned GET against a public market endpoint
+func (b *BitgetAPI) getPublic(endpoint string, queryParams map[string]string, result interface{}) error {
+        values := url.Values{}
+        for k, v := range queryParams {
+                values.Add(k, v)
         }
 
-        resp, err := b.Client.Do(req)
+        fullURL := b.BaseURL + endpoint
+        if len(values) > 0 {
+                fullURL += "?" + values.Encode()
+        }
+
+        resp, err := b.Client.Get(fullURL)
         if err != nil {
-                return nil, fmt.Errorf("request failed: %w", err)
+                return fmt.Errorf("request failed: %w", err)
         }
         defer resp.Body.Close()
 
-        localTimeAfter := time.Now()
-        
-        var serverTimeResp ServerTimeResponse
-        if err := json.NewDecoder(resp.Body).Decode(&serverTimeResp); err != nil {
-                return nil, fmt.Errorf("failed to decode response: %w", err)
+        respBody, err := io.ReadAll(resp.Body)
+        if err != nil {
+                return fmt.Errorf("failed to read response: %w", err)
         }
 
-        if serverTimeResp.Code != "00000" {
-                return nil, fmt.Errorf("server error: %s", serverTimeResp.Msg)
+        var apiResp APIResponse
+        if err := json.Unmarshal(respBody, &apiResp); err != nil {
+                return fmt.Errorf("failed to parse API response: %w", err)
+        }
+
+        if apiResp.Code != "00000" {
+                return fmt.Errorf("API error: %s - %s", apiResp.Code, apiResp.Message)
         }
 
-        // Parse server time (milliseconds)
-        serverTimeMs, err := strconv.ParseInt(serverTimeResp.Data.ServerTime, 10, 64)
+        if result != nil && apiResp.Data != nil {
+                dataBytes, _ := json.Marshal(apiResp.Data)
+                if err := json.Unmarshal(dataBytes, result); err != nil {
+                        return fmt.Errorf("failed to parse response data: %w", err)
+                }
+        }
+
+        return nil
+}
+
+// GetContracts returns USDT-M perpetual contract configs (public endpoint, optional symbol filter)
+func (b *BitgetAPI) GetContracts(symbol string) ([]ContractInfo, error) {
+        queryParams := map[string]string{
+                "productType": "USDT-FUTURES",
+        }
+        if symbol != "" {
+                queryParams["symbol"] = symbol
+        }
+
+        var contracts []ContractInfo
+        if err := b.getPublic("/api/v2/mix/market/contracts", queryParams, &contracts); err != nil {
+                return nil, err
+        }
+
+        return contracts, nil
+}
+
+// OrderBook is the top of a contract's book, best levels first; each level is [price, size]
+type OrderBook struct {
+        Asks [][2]float64
+        Bids [][2]float64
+}
+
+// errEmptyOrderBook means a side of the book has no levels (e.g. a contract not trading yet)
+var errEmptyOrderBook = errors.New("empty order book")
+
+// GetOrderBook returns up to limit merged depth levels per side (public endpoint)
+func (b *BitgetAPI) GetOrderBook(symbol string, limit int) (*OrderBook, error) {
+        var raw struct {
+                Asks [][]interface{} `json:"asks"`
+                Bids [][]interface{} `json:"bids"`
+        }
+        err := b.getPublic("/api/v2/mix/market/merge-depth", map[string]string{
+                "symbol":      symbol,
+                "productType": "USDT-FUTURES",
+                "limit":       strconv.Itoa(limit),
+        }, &raw)
         if err != nil {
-                return nil, fmt.Errorf("failed to parse server time: %w", err)
+                return nil, err
         }
-        
-        serverTime := time.UnixMilli(serverTimeMs)
-        
-        // Calculate network latency (round-trip time / 2)
-        roundTripTime := localTimeAfter.Sub(localTimeBefore)
-        networkLatency := roundTripTime / 2
-        
-        // Adjust server time for network latency
-        adjustedServerTime := serverTime.Add(networkLatency)
-        
-        // Calculate clock offset
-        clockOffset := adjustedServerTime.Sub(localTimeAfter)
-
-        return &TimeSyncResult{
-                ServerTime:     adjustedServerTime,
-                LocalTime:      localTimeAfter,
-                ClockOffset:    clockOffset,
-                NetworkLatency: networkLatency,
-        }, nil
+
+        book := &OrderBook{Asks: parseBookLevels(raw.Asks), Bids: parseBookLevels(raw.Bids)}
+        if len(book.Asks) == 0 || len(book.Bids) == 0 {
+                return nil, fmt.Errorf("%w for %s", errEmptyOrderBook, symbol)
+        }
+        return book, nil
+}
+
+// parseBookLevels accepts levels as strings or numbers (Bitget returns both)
+func parseBookLevels(raw [][]interface{}) [][2]float64 {
+        levels := make([][2]float64, 0, len(raw))
+        for _, level := range raw {
+                if len(level) < 2 {
+                        continue
+                }
+                price, errPrice := strconv.ParseFloat(fmt.Sprint(level[0]), 64)
+                size, errSize := strconv.ParseFloat(fmt.Sprint(level[1]), 64)
+                if errPrice != nil || errSize != nil {
+                        continue
+                }
+                levels = append(levels, [2]float64{price, size})
+        }
+        return levels
+}
+
+// Candle is one kline bar
+type Candle struct {
+        Start time.Time
+        Open  float64
+        High  float64
+        Low   float64
+        Close float64
+}
+
+// GetCandles returns bars of the given granularity (e.g. "1m") from start onwards, oldest first (public endpoint)
+func (b *BitgetAPI) GetCandles(symbol, granularity string, start time.Time, limit int) ([]Candle, error) {
+        var raw [][]string
+        err := b.getPublic("/api/v2/mix/market/candles", map[string]string{
+                "symbol":      symbol,
+                "productType": "USDT-FUTURES",
+                "granularity": granularity,
+                "startTime":   strconv.FormatInt(start.UnixMilli(), 10),
+                "endTime":     strconv.FormatInt(bitgetNow().UnixMilli(), 10),
+                "limit":       strconv.Itoa(limit),
+        }, &raw)
+        if err != nil {
+                return nil, err
+        }
+
+        candles := make([]Candle, 0, len(raw))
+        for _, bar := range raw {
+                if len(bar) < 5 {
+                        continue
+                }
+                startMs, _ := strconv.ParseInt(bar[0], 10, 64)
+                var ohlc [4]float64
+                for i := range ohlc {
+                        ohlc[i], _ = strconv.ParseFloat(bar[i+1], 64)
+                }
+                candles = append(candles, Candle{
+                        Start: time.UnixMilli(startMs),
+                        Open:  ohlc[0],
+                        High:  ohlc[1],
+                        Low:   ohlc[2],
+                        Close: ohlc[3],
+                })
+        }
+        sort.Slice(candles, func(i, j int) bool { return candles[i].Start.Before(candles[j].Start) })
+        return candles, nil
+}
+
+// LaunchedAt returns the contract launch time, zero if Bitget did not report one
+func (c ContractInfo) LaunchedAt() time.Time {
+        launchMs, err := strconv.ParseInt(c.LaunchTime, 10, 64)
+        if err != nil || launchMs <= 0 {
+                return time.Time{}
+        }
+        return time.UnixMilli(launchMs)
+}
+
+// GetServerTime retrieves Bitget server timestamp for time synchronization
+func (b *BitgetAPI) GetServerTime() (*TimeSyncResult, error) {
+        sample, err := timesync.BitgetSample(b.Client, b.BaseURL)
+        if err != nil {
+                return nil, err
+        }
+        return &sample, nil
 }
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	json "github.com/json-iterator/go"
+)
+
+const (
+	// canarySymbol is reserved for synthetic notices; they are encoded for upbit_new.json and
+	// dispatched like a listing, but never written, alerted, published or ordered
+	canarySymbol = "ZZCANARY"
+
+	defaultCanaryInterval     = 5 * time.Minute
+	defaultCanaryLatencyAlert = 100 * time.Millisecond
+	canaryDeliveryTimeout     = 5 * time.Second
+	canaryDedupeGrace         = 200 * time.Millisecond // Wait for a duplicate callback that must not come
+	canaryHistorySize         = 20
+	canaryRegressionFactor    = 4                     // Latency this many times the recent median is a regression
+	canaryRegressionFloor     = 20 * time.Millisecond // Ignore relative jumps below this (GC noise)
+)
+
+// pipelineCanary is set by main when CANARY_ENABLED=true; DispatchNotice reports canary notices to it
+var pipelineCanary *Canary
+
+// isCanarySymbol reports whether a ticker is the reserved canary ticker
+func isCanarySymbol(symbol string) bool {
+	return symbol == canarySymbol
+}
+
+// CanaryStatus is the result of the latest canary run
+type CanaryStatus struct {
+	Runs          int
+	LastRun       time.Time
+	LastLatency   time.Duration // Injection to callback delivery
+	MedianLatency time.Duration // Over recent successful runs
+	Failed        bool
+	LastError     string
+	Regressed     bool
+}
+
+// Canary injects a synthetic listing notice through processAnnouncements, the listing
+// callback and the dispatcher up to order placement, measuring end-to-end latency
+type Canary struct {
+	monitor      *UpbitMonitor
+	interval     time.Duration
+	latencyAlert time.Duration // Absolute latency ceiling
+
+	mu        sync.Mutex
+	seq       int
+	delivered chan ListingInfo
+	history   []time.Duration
+	status    CanaryStatus
+}
+
+// NewCanary returns nil unless CANARY_ENABLED=true; reads CANARY_INTERVAL_SEC and CANARY_LATENCY_ALERT_MS
+func NewCanary(monitor *UpbitMonitor) *Canary {
+	if os.Getenv("CANARY_ENABLED") != "true" {
+		return nil
+	}
+	return &Canary{
+		monitor:      monitor,
+		interval:     envDuration("CANARY_INTERVAL_SEC", time.Second, defaultCanaryInterval),
+		latencyAlert: envDuration("CANARY_LATENCY_ALERT_MS", time.Millisecond, defaultCanaryLatencyAlert),
+		delivered:    make(chan ListingInfo, 4),
+	}
+}
+
+// Handle receives a canary notice once DispatchNotice has run it up to order placement
+func (c *Canary) Handle(listings []ListingInfo) {
+	if len(listings) == 0 || !listings[0].Canary {
+		return
+	}
+	listing := listings[0]
+	if c == nil {
+		log.Printf("⚠️ Canary listing %s dispatched with canary disabled", listing.Symbol)
+		return
+	}
+	select {
+	case c.delivered <- listing:
+	default:
+	}
+}
+
+// Run injects a canary every interval until stop is closed
+func (c *Canary) Run(stop <-chan struct{}) {
+	log.Printf("🐤 Pipeline canary enabled (every %v, latency alert %v)", c.interval, c.latencyAlert)
+	c.runOnce()
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.runOnce()
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (c *Canary) runOnce() {
+	latency, err := c.inject()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.status.Runs++
+	c.status.LastRun = time.Now()
+	c.status.LastLatency = latency
+	c.status.Failed = err != nil
+	c.status.LastError = ""
+	c.status.Regressed = false
+	if err != nil {
+		c.status.LastError = err.Error()
+		log.Printf("❌ Canary failed: %v", err)
+		return
+	}
+
+	median := c.status.MedianLatency
+	c.status.Regressed = latency > c.latencyAlert ||
+		(len(c.history) >= 5 && latency > canaryRegressionFactor*median && latency > canaryRegressionFloor)
+	if c.status.Regressed {
+		log.Printf("⚠️ Canary latency %v (median %v, limit %v)", latency, median, c.latencyAlert)
+	}
+
+	c.history = append(c.history, latency)
+	if len(c.history) > canaryHistorySize {
+		c.history = c.history[len(c.history)-canaryHistorySize:]
+	}
+	sorted := append([]time.Duration(nil), c.history...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	c.status.MedianLatency = durationPercentile(sorted, 0.5)
+}
+
+// inject feeds a synthetic notice list through the real decode, filter, extract, dedupe,
+// upbit_new.json encoding, callback and dispatcher path, then feeds it again to check the
+// duplicate is suppressed
+func (c *Canary) inject() (time.Duration, error) {
+	c.mu.Lock()
+	c.seq++
+	noticeID := -c.seq // Negative IDs never collide with Upbit's
+	c.mu.Unlock()
+
+	// Drop leftovers from a previous run that timed out
+	for len(c.delivered) > 0 {
+		<-c.delivered
+	}
+	c.monitor.forgetTicker(canarySymbol)
+
+	now := upbitNow()
+	payload, err := json.Marshal(UpbitAPIResponse{
+		Success: true,
+		Data: UpbitData2{Notices: []Announcement{{
+			ID:            noticeID,
+			Title:         fmt.Sprintf("[거래] %s(%s) 신규 거래지원 안내 (KRW, USDT 마켓)", "카나리아", canarySymbol),
+			Category:      "거래",
+			ListedAt:      now.Format(time.RFC3339),
+			FirstListedAt: now.Format(time.RFC3339),
+		}}},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("payload: %w", err)
+	}
+
+	start := time.Now()
+	if err := c.monitor.processAnnouncements(bytes.NewReader(payload), "canary"); err != nil {
+		return 0, fmt.Errorf("processAnnouncements: %w", err)
+	}
+
+	var listing ListingInfo
+	select {
+	case listing = <-c.delivered:
+	case <-time.After(canaryDeliveryTimeout):
+		return 0, fmt.Errorf("not dispatched within %v (filters, callback or dispatcher broken)", canaryDeliveryTimeout)
+	}
+	latency := time.Since(start)
+
+	if listing.NoticeID != noticeID || listing.MatchedRule == "" || listing.Source != ListingSourceUpbit {
+		return latency, fmt.Errorf("unexpected listing: notice %d, rule %q, source %q", listing.NoticeID, listing.MatchedRule, listing.Source)
+	}
+	if len(listing.Markets) != 2 || listing.Markets[0] != "KRW" || listing.Markets[1] != "USDT" {
+		return latency, fmt.Errorf("markets extracted as %v, want [KRW USDT]", listing.Markets)
+	}
+
+	if err := c.monitor.processAnnouncements(bytes.NewReader(payload), "canary"); err != nil {
+		return latency, fmt.Errorf("processAnnouncements (repeat): %w", err)
+	}
+	select {
+	case <-c.delivered:
+		return latency, fmt.Errorf("duplicate notice dispatched twice (dedupe broken)")
+	case <-time.After(canaryDedupeGrace):
+	}
+	return latency, nil
+}
+
+// Status returns the latest canary result
+func (c *Canary) Status() CanaryStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.status
+}
+
+// forgetTicker removes a ticker from the dedupe cache so it is detected again
+func (um *UpbitMonitor) forgetTicker(symbol string) {
+	um.mu.Lock()
+	delete(um.cachedTickers, symbol)
+	um.mu.Unlock()
+}
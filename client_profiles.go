@@ -0,0 +1,289 @@
+package main
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	utls "github.com/refraction-networking/utls"
+)
+
+// Browser families share TLS, HTTP/2 and header conventions
+const (
+	BrowserChrome  = "chrome"
+	BrowserEdge    = "edge"
+	BrowserFirefox = "firefox"
+	BrowserSafari  = "safari"
+)
+
+// ClientProfile keeps the User-Agent, TLS ClientHello, ALPN/HTTP2 settings and request
+// headers of one browser consistent. The ClientHello is built by uTLS from the browser's
+// recorded spec (GREASE, extension and cipher suite order, curves, ALPN), so it matches
+// the browser byte for byte apart from the random GREASE values and shuffled extensions
+// Chrome itself randomizes; `go run . tlsprobe` shows what each profile really sends.
+type ClientProfile struct {
+	Name      string
+	Family    string
+	UserAgent string
+	SecChUa   string // Chromium brand list; empty for Firefox/Safari, which never send client hints
+	Platform  string // sec-ch-ua-platform value
+
+	Hello      utls.ClientHelloID // Browser ClientHello uTLS sends
+	NextProtos []string           // ALPN offered by Hello
+
+	// HTTP/2 SETTINGS and connection window the browser announces
+	H2HeaderTableSize    int
+	H2InitialWindowSize  int
+	H2ConnectionWindow   int
+	H2MaxFrameSize       int
+	MaxResponseHeaderLen int64
+
+	AcceptLanguage string
+	AcceptEncoding string
+}
+
+func chromiumProfile(name, family, userAgent, secChUa, platform string) ClientProfile {
+	return ClientProfile{
+		Name:                 name,
+		Family:               family,
+		UserAgent:            userAgent,
+		SecChUa:              secChUa,
+		Platform:             platform,
+		Hello:                utls.HelloChrome_120, // Chrome 118-120 and Edge 120 send the same hello
+		NextProtos:           []string{"h2", "http/1.1"},
+		H2HeaderTableSize:    65536,
+		H2InitialWindowSize:  6291456,
+		H2ConnectionWindow:   15728640,
+		MaxResponseHeaderLen: 262144,
+		AcceptLanguage:       "ko-KR,ko;q=0.9,en-US;q=0.8,en;q=0.7",
+		AcceptEncoding:       "gzip, deflate, br",
+	}
+}
+
+func firefoxProfile(name, userAgent string) ClientProfile {
+	return ClientProfile{
+		Name:                name,
+		Family:              BrowserFirefox,
+		UserAgent:           userAgent,
+		Hello:               utls.HelloFirefox_120,
+		NextProtos:          []string{"h2", "http/1.1"},
+		H2HeaderTableSize:   65536,
+		H2InitialWindowSize: 131072,
+		H2ConnectionWindow:  12582912,
+		H2MaxFrameSize:      16384,
+		AcceptLanguage:      "ko-KR,ko;q=0.8,en-US;q=0.5,en;q=0.3",
+		AcceptEncoding:      "gzip, deflate, br",
+	}
+}
+
+func safariProfile(name, userAgent string) ClientProfile {
+	return ClientProfile{
+		Name:                name,
+		Family:              BrowserSafari,
+		UserAgent:           userAgent,
+		Hello:               utls.HelloSafari_16_0, // Safari 17 kept the Safari 16 hello
+		NextProtos:          []string{"h2", "http/1.1"},
+		H2InitialWindowSize: 4194304,
+		H2ConnectionWindow:  10551295,
+		AcceptLanguage:      "ko-KR,ko;q=0.9",
+		AcceptEncoding:      "gzip, deflate, br",
+	}
+}
+
+// clientProfiles is the pool of browser identities (the former userAgents list)
+var clientProfiles = []ClientProfile{
+	// Chrome on Windows
+	chromiumProfile("chrome120-win", BrowserChrome,
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+		`"Not_A Brand";v="8", "Chromium";v="120", "Google Chrome";v="120"`, `"Windows"`),
+	chromiumProfile("chrome119-win", BrowserChrome,
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/119.0.0.0 Safari/537.36",
+		`"Google Chrome";v="119", "Chromium";v="119", "Not?A_Brand";v="24"`, `"Windows"`),
+	chromiumProfile("chrome118-win", BrowserChrome,
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Safari/537.36",
+		`"Chromium";v="118", "Google Chrome";v="118", "Not=A?Brand";v="99"`, `"Windows"`),
+	// Chrome on macOS
+	chromiumProfile("chrome120-mac", BrowserChrome,
+		"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+		`"Not_A Brand";v="8", "Chromium";v="120", "Google Chrome";v="120"`, `"macOS"`),
+	chromiumProfile("chrome119-mac", BrowserChrome,
+		"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/119.0.0.0 Safari/537.36",
+		`"Google Chrome";v="119", "Chromium";v="119", "Not?A_Brand";v="24"`, `"macOS"`),
+	// Firefox on Windows / macOS
+	firefoxProfile("firefox121-win", "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:121.0) Gecko/20100101 Firefox/121.0"),
+	firefoxProfile("firefox120-win", "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:120.0) Gecko/20100101 Firefox/120.0"),
+	firefoxProfile("firefox121-mac", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10.15; rv:121.0) Gecko/20100101 Firefox/121.0"),
+	// Safari on macOS
+	safariProfile("safari17.2-mac", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Safari/605.1.15"),
+	safariProfile("safari17.1-mac", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Safari/605.1.15"),
+	// Edge on Windows
+	chromiumProfile("edge120-win", BrowserEdge,
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.0.0",
+		`"Not_A Brand";v="8", "Chromium";v="120", "Microsoft Edge";v="120"`, `"Windows"`),
+}
+
+// enabledClientProfiles filters clientProfiles by UPBIT_CLIENT_PROFILES (profile names or families)
+func enabledClientProfiles() []ClientProfile {
+	raw := strings.TrimSpace(os.Getenv("UPBIT_CLIENT_PROFILES"))
+	if raw == "" {
+		return clientProfiles
+	}
+
+	wanted := make(map[string]bool)
+	for _, part := range strings.Split(raw, ",") {
+		wanted[strings.ToLower(strings.TrimSpace(part))] = true
+	}
+
+	var enabled []ClientProfile
+	for _, profile := range clientProfiles {
+		if wanted[profile.Name] || wanted[profile.Family] {
+			enabled = append(enabled, profile)
+		}
+	}
+	if len(enabled) == 0 {
+		log.Printf("⚠️ UPBIT_CLIENT_PROFILES '%s' matched no profile, using all", raw)
+		return clientProfiles
+	}
+	return enabled
+}
+
+// profileForProxy sticks each proxy to one profile, so an egress IP keeps the same
+// browser identity across requests and restarts
+func profileForProxy(proxyID string, profiles []ClientProfile) ClientProfile {
+	h := fnv.New32a()
+	h.Write([]byte(proxyID))
+	return profiles[int(h.Sum32()%uint32(len(profiles)))]
+}
+
+// ConfigureTransport sends the profile's ClientHello through uTLS and applies its ALPN and
+// HTTP/2 settings. The TLS dial goes over transport.DialContext; of TLSClientConfig only
+// RootCAs is honoured (the probe server trusts its own certificate that way).
+// HTTP/2 over a uTLS connection needs the Go 1.27 net/http, which accepts any conn
+// reporting a tls.ConnectionState.
+func (p ClientProfile) ConfigureTransport(transport *http.Transport) {
+	transport.TLSClientConfig = &tls.Config{NextProtos: p.NextProtos}
+	transport.ForceAttemptHTTP2 = true // Custom dialers disable HTTP/2 unless forced
+	transport.MaxResponseHeaderBytes = p.MaxResponseHeaderLen
+	transport.HTTP2 = &http.HTTP2Config{
+		MaxDecoderHeaderTableSize:     p.H2HeaderTableSize,
+		MaxReceiveBufferPerStream:     p.H2InitialWindowSize,
+		MaxReceiveBufferPerConnection: p.H2ConnectionWindow,
+		MaxReadFrameSize:              p.H2MaxFrameSize,
+	}
+
+	// Per-client session cache: reconnects resume TLS instead of a full handshake
+	sessions := utls.NewLRUClientSessionCache(8)
+	transport.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dial := transport.DialContext
+		if dial == nil {
+			dial = (&net.Dialer{Timeout: 5 * time.Second}).DialContext
+		}
+		raw, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			raw.Close()
+			return nil, err
+		}
+		config := &utls.Config{ServerName: host, ClientSessionCache: sessions}
+		if transport.TLSClientConfig != nil {
+			config.RootCAs = transport.TLSClientConfig.RootCAs
+		}
+		if transport.TLSHandshakeTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, transport.TLSHandshakeTimeout)
+			defer cancel()
+		}
+
+		conn := utls.UClient(raw, config, p.Hello)
+		if err := conn.HandshakeContext(ctx); err != nil {
+			raw.Close()
+			return nil, err
+		}
+		return utlsConn{conn}, nil
+	}
+}
+
+// utlsConn reports its state as a crypto/tls ConnectionState, which is how net/http
+// learns the negotiated ALPN and switches the connection to HTTP/2
+type utlsConn struct {
+	*utls.UConn
+}
+
+func (c utlsConn) ConnectionState() tls.ConnectionState {
+	state := c.UConn.ConnectionState()
+	return tls.ConnectionState{
+		Version:                    state.Version,
+		HandshakeComplete:          state.HandshakeComplete,
+		DidResume:                  state.DidResume,
+		CipherSuite:                state.CipherSuite,
+		NegotiatedProtocol:         state.NegotiatedProtocol,
+		NegotiatedProtocolIsMutual: true,
+		ServerName:                 state.ServerName,
+		PeerCertificates:           state.PeerCertificates,
+		VerifiedChains:             state.VerifiedChains,
+	}
+}
+
+// isGREASE reports whether a cipher suite, curve or extension ID is a GREASE value (RFC 8701)
+func isGREASE(v uint16) bool {
+	return v>>8 == v&0xff && v&0x0f == 0x0a
+}
+
+// ApplyHeaders sets the header set this browser sends on a cross-site XHR to Upbit's API
+func (p ClientProfile) ApplyHeaders(req *http.Request) {
+	req.Header.Set("User-Agent", p.UserAgent)
+	req.Header.Set("Accept", "application/json, text/plain, */*")
+	req.Header.Set("Accept-Language", p.AcceptLanguage)
+	req.Header.Set("Accept-Encoding", p.AcceptEncoding)
+	req.Header.Set("Referer", "https://upbit.com/")
+	req.Header.Set("Origin", "https://upbit.com")
+	req.Header.Set("Sec-Fetch-Dest", "empty")
+	req.Header.Set("Sec-Fetch-Mode", "cors")
+	req.Header.Set("Sec-Fetch-Site", "same-site")
+	// Ask CDN edges to revalidate instead of serving a cached notice list
+	req.Header.Set("Cache-Control", "no-cache")
+	req.Header.Set("Pragma", "no-cache")
+
+	// Client hints exist only in Chromium browsers
+	if p.SecChUa != "" {
+		req.Header.Set("Sec-Ch-Ua", p.SecChUa)
+		req.Header.Set("Sec-Ch-Ua-Mobile", "?0")
+		req.Header.Set("Sec-Ch-Ua-Platform", p.Platform)
+	}
+}
+
+// decodeResponseBody undoes Content-Encoding; Go only decompresses automatically
+// when it chose Accept-Encoding itself, and profiles set it explicitly. Every encoding
+// a profile advertises must be handled here.
+func decodeResponseBody(resp *http.Response) (io.ReadCloser, error) {
+	switch strings.ToLower(resp.Header.Get("Content-Encoding")) {
+	case "", "identity":
+		return resp.Body, nil
+	case "gzip":
+		return gzip.NewReader(resp.Body)
+	case "deflate":
+		return zlib.NewReader(resp.Body) // HTTP "deflate" is the zlib format (RFC 9110 8.4.1.2)
+	case "br":
+		return struct {
+			io.Reader
+			io.Closer
+		}{brotli.NewReader(resp.Body), resp.Body}, nil
+	default:
+		return nil, fmt.Errorf("unsupported Content-Encoding %q", resp.Header.Get("Content-Encoding"))
+	}
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	utls "github.com/refraction-networking/utls"
+)
+
+// helloSpec returns the cipher suites and curves of a uTLS hello with GREASE values
+// folded to one placeholder, so a sent hello compares equal regardless of the random picks
+func helloSpec(t *testing.T, id utls.ClientHelloID) (suites []uint16, curves []uint16) {
+	t.Helper()
+	spec, err := utls.UTLSIdToSpec(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	suites = foldGREASE(spec.CipherSuites)
+	for _, ext := range spec.Extensions {
+		if supported, ok := ext.(*utls.SupportedCurvesExtension); ok {
+			for _, curve := range supported.Curves {
+				curves = append(curves, uint16(curve))
+			}
+		}
+	}
+	return suites, foldGREASE(curves)
+}
+
+func foldGREASE(ids []uint16) []uint16 {
+	folded := make([]uint16, len(ids))
+	for i, id := range ids {
+		if isGREASE(id) {
+			id = utls.GREASE_PLACEHOLDER
+		}
+		folded[i] = id
+	}
+	return folded
+}
+
+func TestClientProfilesAgainstProbeServer(t *testing.T) {
+	ps, err := startProbeServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ps.Close()
+
+	for _, profile := range clientProfiles {
+		t.Run(profile.Name, func(t *testing.T) {
+			seen, err := ps.Probe(profile)
+			if err != nil {
+				t.Fatalf("probe request failed: %v", err)
+			}
+			if seen.hello == nil {
+				t.Fatal("server saw no ClientHello")
+			}
+
+			if !reflect.DeepEqual(seen.hello.SupportedProtos, profile.NextProtos) {
+				t.Errorf("ALPN = %v, want %v", seen.hello.SupportedProtos, profile.NextProtos)
+			}
+			if seen.proto != "HTTP/2.0" {
+				t.Errorf("negotiated %s, want HTTP/2.0", seen.proto)
+			}
+
+			wantSuites, wantCurves := helloSpec(t, profile.Hello)
+			if got := foldGREASE(seen.hello.CipherSuites); !reflect.DeepEqual(got, wantSuites) {
+				t.Errorf("cipher suites = %x, want %x (in order)", got, wantSuites)
+			}
+			curves := make([]uint16, len(seen.hello.SupportedCurves))
+			for i, curve := range seen.hello.SupportedCurves {
+				curves[i] = uint16(curve)
+			}
+			if got := foldGREASE(curves); !reflect.DeepEqual(got, wantCurves) {
+				t.Errorf("curves = %x, want %x (in order)", got, wantCurves)
+			}
+			// Chromium and Safari lead their suites with a GREASE value; Firefox and crypto/tls never do
+			wantGREASE := profile.Family != BrowserFirefox
+			if grease := isGREASE(seen.hello.CipherSuites[0]); grease != wantGREASE {
+				t.Errorf("GREASE first suite = %v, want %v for %s", grease, wantGREASE, profile.Family)
+			}
+
+			wantHeaders := map[string]string{
+				"User-Agent":      profile.UserAgent,
+				"Accept-Language": profile.AcceptLanguage,
+				"Accept-Encoding": profile.AcceptEncoding,
+				"Origin":          "https://upbit.com",
+				"Cache-Control":   "no-cache",
+				"Pragma":          "no-cache",
+				"Sec-Ch-Ua":       profile.SecChUa,
+			}
+			for name, want := range wantHeaders {
+				if got := seen.headers.Get(name); got != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+			chromium := profile.Family == BrowserChrome || profile.Family == BrowserEdge
+			if hasHints := seen.headers.Get("Sec-Ch-Ua-Platform") != ""; hasHints != chromium {
+				t.Errorf("client hints sent = %v, want %v for %s", hasHints, chromium, profile.Family)
+			}
+		})
+	}
+}
+
+// TestProfileHelloThroughConnectProxy checks that an HTTP proxy tunnel still carries the
+// profile's uTLS hello rather than a crypto/tls one from the Transport
+func TestProfileHelloThroughConnectProxy(t *testing.T) {
+	ps, err := startProbeServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ps.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	wantAuth := "Basic " + base64.StdEncoding.EncodeToString([]byte("user:secret"))
+	go func() {
+		for {
+			client, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer client.Close()
+				req, err := http.ReadRequest(bufio.NewReader(client))
+				if err != nil || req.Method != http.MethodConnect {
+					return
+				}
+				if req.Header.Get("Proxy-Authorization") != wantAuth {
+					fmt.Fprint(client, "HTTP/1.1 407 Proxy Authentication Required\r\n\r\n")
+					return
+				}
+				upstream, err := net.Dial("tcp", req.Host)
+				if err != nil {
+					return
+				}
+				defer upstream.Close()
+				fmt.Fprint(client, "HTTP/1.1 200 Connection established\r\n\r\n")
+				go io.Copy(upstream, client)
+				io.Copy(client, upstream)
+			}()
+		}
+	}()
+
+	profile := clientProfiles[0]
+	pc, err := newProxyClient(ProxyConfig{URL: "http://user:secret@" + listener.Addr().String(), Protocol: ProxyProtocolHTTP}, profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pc.transport.TLSClientConfig.RootCAs = ps.Pool
+	defer pc.transport.CloseIdleConnections()
+
+	resp, err := pc.client.Get(ps.URL)
+	if err != nil {
+		t.Fatalf("request through proxy failed: %v", err)
+	}
+	resp.Body.Close()
+
+	ps.mu.Lock()
+	seen := ps.last
+	ps.mu.Unlock()
+	if seen.hello == nil || !isGREASE(seen.hello.CipherSuites[0]) {
+		t.Fatalf("hello through proxy is not %s's uTLS hello: %+v", profile.Name, seen.hello)
+	}
+	if seen.proto != "HTTP/2.0" {
+		t.Errorf("negotiated %s, want HTTP/2.0", seen.proto)
+	}
+
+	bad, err := newProxyClient(ProxyConfig{URL: "http://user:wrong@" + listener.Addr().String(), Protocol: ProxyProtocolHTTP}, profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = bad.client.Get(ps.URL)
+	if err == nil || !strings.Contains(err.Error(), "407") || strings.Contains(err.Error(), "wrong") {
+		t.Errorf("rejected CONNECT error = %v, want the 407 status without credentials", err)
+	}
+}
+
+func TestDecodeResponseBody(t *testing.T) {
+	const payload = `{"success":true,"data":{"notices":[]}}`
+
+	compress := map[string]func(io.Writer) io.WriteCloser{
+		"gzip":    func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) },
+		"deflate": func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) },
+		"br":      func(w io.Writer) io.WriteCloser { return brotli.NewWriter(w) },
+	}
+
+	tests := []struct {
+		encoding string
+		wantErr  bool
+	}{
+		{"", false},
+		{"identity", false},
+		{"gzip", false},
+		{"deflate", false},
+		{"br", false},
+		{"zstd", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.encoding, func(t *testing.T) {
+			var body bytes.Buffer
+			if newWriter, ok := compress[tt.encoding]; ok {
+				w := newWriter(&body)
+				w.Write([]byte(payload))
+				w.Close()
+			} else {
+				body.WriteString(payload)
+			}
+
+			resp := &http.Response{Header: http.Header{}, Body: io.NopCloser(&body)}
+			if tt.encoding != "" {
+				resp.Header.Set("Content-Encoding", tt.encoding)
+			}
+			reader, err := decodeResponseBody(resp)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer reader.Close()
+			got, err := io.ReadAll(reader)
+			if err != nil || string(got) != payload {
+				t.Errorf("decoded %q (%v), want %q", got, err, payload)
+			}
+		})
+	}
+}
+
+// Every encoding a profile advertises must be decodable, or responses are lost as parse failures
+func TestAdvertisedEncodingsAreDecodable(t *testing.T) {
+	for _, profile := range clientProfiles {
+		for _, encoding := range strings.Split(profile.AcceptEncoding, ",") {
+			name := strings.TrimSpace(encoding)
+			resp := &http.Response{
+				Header: http.Header{"Content-Encoding": {name}},
+				Body:   io.NopCloser(bytes.NewReader(nil)),
+			}
+			if _, err := decodeResponseBody(resp); err != nil && strings.Contains(err.Error(), "unsupported") {
+				t.Errorf("%s advertises %q but decodeResponseBody rejects it", profile.Name, name)
+			}
+		}
+	}
+}
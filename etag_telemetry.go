@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	json "github.com/json-iterator/go"
+)
+
+// etagTelemetryRetain bounds how many recent ETags are remembered for per-proxy dedupe
+const etagTelemetryRetain = 64
+
+// ETagTelemetry is the append-only store of ETag transitions in etag_news.json.
+// Every proxy's first observation of a new ETag is recorded, so races between proxies
+// can be replayed: the earliest observation is the winner, the rest lag behind it.
+type ETagTelemetry struct {
+	path  string
+	mu    sync.Mutex
+	seen  map[string]map[string]bool // new ETag -> proxy IDs that already reported it
+	order []string                   // ETags in first-seen order, oldest first
+}
+
+// NewETagTelemetry opens the store at path; the file is created on first record
+func NewETagTelemetry(path string) *ETagTelemetry {
+	return &ETagTelemetry{path: path, seen: make(map[string]map[string]bool)}
+}
+
+// Record appends entry unless this proxy already reported the same new ETag.
+// It reports whether the entry was this proxy's first observation.
+func (t *ETagTelemetry) Record(entry ETagChangeLog) (bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	proxies, exists := t.seen[entry.NewETag]
+	if !exists {
+		proxies = make(map[string]bool)
+		t.seen[entry.NewETag] = proxies
+		t.order = append(t.order, entry.NewETag)
+		if len(t.order) > etagTelemetryRetain {
+			delete(t.seen, t.order[0])
+			t.order = t.order[1:]
+		}
+	}
+	if proxies[entry.ProxyID] {
+		return false, nil
+	}
+	proxies[entry.ProxyID] = true
+
+	file, err := os.OpenFile(t.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return true, fmt.Errorf("error opening etag log file for append: %v", err)
+	}
+	defer file.Close()
+
+	jsonData, err := json.Marshal(entry)
+	if err != nil {
+		return true, fmt.Errorf("error marshaling etag log: %v", err)
+	}
+	if _, err := file.Write(append(jsonData, '\n')); err != nil {
+		return true, fmt.Errorf("error writing to etag log file: %v", err)
+	}
+	return true, nil
+}
+
+// Load reads every entry. The file may hold the legacy {"detections": [...]} object,
+// JSONL lines, or the object followed by appended lines.
+func (t *ETagTelemetry) Load() ([]ETagChangeLog, error) {
+	t.mu.Lock()
+	data, err := os.ReadFile(t.path)
+	t.mu.Unlock()
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", t.path, err)
+	}
+
+	var entries []ETagChangeLog
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for decoder.More() {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err == io.EOF {
+			break
+		} else if err != nil {
+			log.Printf("⚠️ %s: stopped reading at a malformed entry: %v", t.path, err)
+			break
+		}
+
+		var legacy struct {
+			Detections []ETagChangeLog `json:"detections"`
+		}
+		if err := json.Unmarshal(raw, &legacy); err == nil && legacy.Detections != nil {
+			entries = append(entries, legacy.Detections...)
+			continue
+		}
+
+		var entry ETagChangeLog
+		if err := json.Unmarshal(raw, &entry); err != nil || entry.NewETag == "" {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// ProxyRaceStats is one proxy's record across ETag races
+type ProxyRaceStats struct {
+	Key              string
+	Name             string
+	Observations     int
+	Wins             int
+	WinRate          float64       // Wins / races in the window
+	MedianLag        time.Duration // Behind the winner, losses only
+	P90Lag           time.Duration
+	MedianDateToSeen time.Duration // Upbit Date header to our receipt
+}
+
+// ProxyRaceReport summarizes ETag races in a time window
+type ProxyRaceReport struct {
+	Since   time.Time
+	Races   int
+	Entries int
+	Proxies []ProxyRaceStats // Most wins first
+}
+
+// BuildProxyRaceReport groups entries by new ETag and scores proxies against each race's winner.
+// Startup baselines (no previous ETag) are not races and are skipped. Legacy entries without a
+// proxy ID are matched to current proxies by display name.
+func BuildProxyRaceReport(entries []ETagChangeLog, since time.Time, proxies []ProxyConfig) ProxyRaceReport {
+	idByName := make(map[string]string, len(proxies))
+	for _, config := range proxies {
+		idByName[config.DisplayName()] = config.ID()
+	}
+	keyOf := func(entry ETagChangeLog) string {
+		if entry.ProxyID != "" {
+			return entry.ProxyID
+		}
+		if id, exists := idByName[entry.ProxyName]; exists {
+			return id
+		}
+		return "name:" + entry.ProxyName
+	}
+
+	type observation struct {
+		key        string
+		name       string
+		receivedAt time.Time
+		serverDate time.Time
+	}
+	races := make(map[string][]observation)
+	report := ProxyRaceReport{Since: since}
+
+	for _, entry := range entries {
+		if entry.OldETag == "" || entry.OldETag == entry.NewETag {
+			continue
+		}
+		receivedAt, err := time.Parse(time.RFC3339Nano, entry.ServerTime)
+		if err != nil || receivedAt.Before(since) {
+			continue
+		}
+		obs := observation{key: keyOf(entry), name: entry.ProxyName, receivedAt: receivedAt}
+		if entry.ServerDate != "" {
+			obs.serverDate, _ = http.ParseTime(entry.ServerDate)
+		}
+		races[entry.NewETag] = append(races[entry.NewETag], obs)
+		report.Entries++
+	}
+	report.Races = len(races)
+
+	type accumulator struct {
+		stats      ProxyRaceStats
+		lags       []time.Duration
+		dateToSeen []time.Duration
+	}
+	perProxy := make(map[string]*accumulator)
+
+	for _, observations := range races {
+		sort.Slice(observations, func(i, j int) bool {
+			return observations[i].receivedAt.Before(observations[j].receivedAt)
+		})
+		winnerAt := observations[0].receivedAt
+		counted := make(map[string]bool)
+		for i, obs := range observations {
+			if counted[obs.key] {
+				continue // Legacy files may hold duplicates
+			}
+			counted[obs.key] = true
+
+			acc := perProxy[obs.key]
+			if acc == nil {
+				acc = &accumulator{stats: ProxyRaceStats{Key: obs.key}}
+				perProxy[obs.key] = acc
+			}
+			acc.stats.Name = obs.name
+			acc.stats.Observations++
+			if i == 0 {
+				acc.stats.Wins++
+			} else {
+				acc.lags = append(acc.lags, obs.receivedAt.Sub(winnerAt))
+			}
+			if !obs.serverDate.IsZero() {
+				acc.dateToSeen = append(acc.dateToSeen, obs.receivedAt.Sub(obs.serverDate))
+			}
+		}
+	}
+
+	for _, acc := range perProxy {
+		if report.Races > 0 {
+			acc.stats.WinRate = float64(acc.stats.Wins) / float64(report.Races)
+		}
+		acc.stats.MedianLag = durationPercentile(acc.lags, 0.5)
+		acc.stats.P90Lag = durationPercentile(acc.lags, 0.9)
+		acc.stats.MedianDateToSeen = durationPercentile(acc.dateToSeen, 0.5)
+		report.Proxies = append(report.Proxies, acc.stats)
+	}
+	sort.Slice(report.Proxies, func(i, j int) bool {
+		a, b := report.Proxies[i], report.Proxies[j]
+		if a.Wins != b.Wins {
+			return a.Wins > b.Wins
+		}
+		return a.MedianLag < b.MedianLag
+	})
+	return report
+}
+
+// durationPercentile returns the p-th percentile (0-1) by nearest rank, 0 for no samples
+func durationPercentile(samples []time.Duration, p float64) time.Duration {
+	if len(samples) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	index := int(p*float64(len(sorted))+0.5) - 1
+	return sorted[max(0, min(index, len(sorted)-1))]
+}
+
+// ProxyRaceReport replays etag_news.json into per-proxy race stats since the given time
+func (um *UpbitMonitor) ProxyRaceReport(since time.Time) (ProxyRaceReport, error) {
+	entries, err := um.etagTelemetry.Load()
+	if err != nil {
+		return ProxyRaceReport{Since: since}, err
+	}
+	return BuildProxyRaceReport(entries, since, um.Proxies()), nil
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"sync"
+	"time"
+)
+
+// EventType names an event published on the internal event bus
+type EventType string
+
+const (
+	EventListingDetected EventType = "listing.detected"
+	EventOrderPlaced     EventType = "order.placed"
+	EventOrderFailed     EventType = "order.failed"
+	EventPositionClosed  EventType = "position.closed"
+	EventProxyCooldown   EventType = "proxy.cooldown"
+	EventWebhookTest     EventType = "webhook.test"
+)
+
+// Event is the envelope delivered to bus subscribers and webhooks
+type Event struct {
+	ID     string      `json:"id"`
+	Type   EventType   `json:"type"`
+	Time   time.Time   `json:"time"`
+	UserID int64       `json:"user_id,omitempty"` // 0 = global event (not tied to a user)
+	Data   interface{} `json:"data"`
+}
+
+// ListingDetectedEvent is published when the monitor finds a new listing
+type ListingDetectedEvent struct {
+	Symbol      string   `json:"symbol"`
+	Source      string   `json:"source"`
+	Markets     []string `json:"markets,omitempty"`
+	NoticeID    int      `json:"notice_id,omitempty"`
+	Title       string   `json:"title,omitempty"`
+	NoticeURL   string   `json:"notice_url,omitempty"`
+	Category    string   `json:"category,omitempty"`
+	MatchedRule string   `json:"matched_rule,omitempty"`
+	Proxy       string   `json:"proxy,omitempty"`
+	ListedAt    string   `json:"listed_at,omitempty"`
+	DetectedAt  string   `json:"detected_at"`
+	LatencyMs   int64    `json:"latency_ms,omitempty"`
+}
+
+// OrderPlacedEvent is published when an entry order is accepted by Bitget
+type OrderPlacedEvent struct {
+	Symbol     string  `json:"symbol"`
+	OrderID    string  `json:"order_id"`
+	Side       string  `json:"side"`
+	Size       float64 `json:"size"`
+	Price      float64 `json:"price"`
+	MarginUSDT float64 `json:"margin_usdt"`
+	Leverage   int     `json:"leverage"`
+}
+
+// OrderFailedEvent is published when an auto-trade could not be placed
+type OrderFailedEvent struct {
+	Symbol     string  `json:"symbol"`
+	MarginUSDT float64 `json:"margin_usdt"`
+	Leverage   int     `json:"leverage"`
+	Error      string  `json:"error"`
+}
+
+// PositionClosedEvent is published when a tracked position is closed
+type PositionClosedEvent struct {
+	Symbol  string `json:"symbol"`
+	OrderID string `json:"order_id,omitempty"`
+	Reason  string `json:"reason"` // manual, close_all, closed_on_exchange, delisting, caution
+}
+
+// ProxyCooldownEvent is published when a proxy is put on cooldown after a rate limit
+type ProxyCooldownEvent struct {
+	ProxyID    string `json:"proxy_id"`
+	ProxyName  string `json:"proxy_name"`
+	StatusCode int    `json:"status_code"`
+	CooldownMs int64  `json:"cooldown_ms"`
+}
+
+// eventSubscriberBuffer is how many events a slow subscriber may lag before drops
+const eventSubscriberBuffer = 256
+
+type eventSubscriber struct {
+	name    string
+	types   map[EventType]bool // nil = all types
+	events  chan Event
+	handler func(Event)
+}
+
+// EventBus fans events out to subscribers without ever blocking the publisher
+type EventBus struct {
+	mu          sync.RWMutex
+	subscribers map[int]*eventSubscriber
+	nextID      int
+}
+
+// eventBus is the process-wide bus shared by the monitor and the Telegram bot
+var eventBus = NewEventBus()
+
+func NewEventBus() *EventBus {
+	return &EventBus{
+		subscribers: make(map[int]*eventSubscriber),
+	}
+}
+
+// Subscribe registers handler for the given types (none = all) and returns an unsubscribe func.
+// Each subscriber runs on its own goroutine, so a slow handler only delays itself.
+func (eb *EventBus) Subscribe(name string, handler func(Event), types ...EventType) func() {
+	sub := &eventSubscriber{
+		name:    name,
+		events:  make(chan Event, eventSubscriberBuffer),
+		handler: handler,
+	}
+	if len(types) > 0 {
+		sub.types = make(map[EventType]bool)
+		for _, t := range types {
+			sub.types[t] = true
+		}
+	}
+
+	eb.mu.Lock()
+	id := eb.nextID
+	eb.nextID++
+	eb.subscribers[id] = sub
+	eb.mu.Unlock()
+
+	go func() {
+		for event := range sub.events {
+			sub.handler(event)
+		}
+	}()
+
+	return func() {
+		eb.mu.Lock()
+		defer eb.mu.Unlock()
+		if _, exists := eb.subscribers[id]; exists {
+			delete(eb.subscribers, id)
+			close(sub.events)
+		}
+	}
+}
+
+// Publish stamps and delivers an event; it drops (and logs) rather than block on a full subscriber
+func (eb *EventBus) Publish(eventType EventType, userID int64, data interface{}) {
+	event := Event{
+		ID:     newEventID(),
+		Type:   eventType,
+		Time:   time.Now().UTC(),
+		UserID: userID,
+		Data:   data,
+	}
+
+	eb.mu.RLock()
+	defer eb.mu.RUnlock()
+
+	for _, sub := range eb.subscribers {
+		if sub.types != nil && !sub.types[eventType] {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			log.Printf("⚠️ Event bus: subscriber %s is full, dropped %s event", sub.name, eventType)
+		}
+	}
+}
+
+// newEventID returns a random hex event ID (used as webhook idempotency key)
+func newEventID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return time.Now().UTC().Format("20060102150405.000000000")
+	}
+	return hex.EncodeToString(buf)
+}
+
+// newListingDetectedEvent converts a ListingInfo into its event payload
+func newListingDetectedEvent(listing ListingInfo) ListingDetectedEvent {
+	event := ListingDetectedEvent{
+		Symbol:      listing.Symbol,
+		Source:      string(listing.Source),
+		Markets:     listing.Markets,
+		NoticeID:    listing.NoticeID,
+		Title:       listing.Title,
+		NoticeURL:   listing.NoticeURL(),
+		Category:    listing.Category,
+		MatchedRule: listing.MatchedRule,
+		Proxy:       listing.Proxy,
+		DetectedAt:  listing.DetectedAt.UTC().Format(time.RFC3339Nano),
+		LatencyMs:   listing.DetectionLatency().Milliseconds(),
+	}
+	if !listing.ListedAt.IsZero() {
+		event.ListedAt = listing.ListedAt.Format(time.RFC3339)
+	}
+	return event
+}
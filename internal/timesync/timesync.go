@@ -0,0 +1,177 @@
+// Package timesync measures exchange clock offsets and tracks them over time.
+//
+// Every sample bounds the offset: the server stamped its clock at some moment between
+// sending the request and receiving the response, and the stamp is truncated to the
+// source's precision (1s for an HTTP Date header, 1ms for Bitget). Intersecting the
+// bounds of recent samples filters network jitter without trusting any single round trip.
+package timesync
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	json "github.com/json-iterator/go"
+)
+
+// Sample is one clock measurement against a server
+type Sample struct {
+	ServerTime     time.Time     // Server clock at LocalTime, latency-adjusted
+	LocalTime      time.Time     // When the response arrived
+	ClockOffset    time.Duration // Server minus local; positive means our clock is behind
+	NetworkLatency time.Duration // Half the round trip
+	MinOffset      time.Duration // Offset bounds implied by the round trip and precision
+	MaxOffset      time.Duration
+}
+
+// NewSample builds a sample from a server timestamp truncated to precision,
+// taken between sentAt and receivedAt on the local clock
+func NewSample(sentAt, receivedAt, serverStamp time.Time, precision time.Duration) Sample {
+	latency := receivedAt.Sub(sentAt) / 2
+	minOffset := serverStamp.Sub(receivedAt)
+	maxOffset := serverStamp.Add(precision).Sub(sentAt)
+	offset := (minOffset + maxOffset) / 2
+
+	return Sample{
+		ServerTime:     receivedAt.Add(offset),
+		LocalTime:      receivedAt,
+		ClockOffset:    offset,
+		NetworkLatency: latency,
+		MinOffset:      minOffset,
+		MaxOffset:      maxOffset,
+	}
+}
+
+// FromDateHeader builds a sample from an HTTP response's Date header (1s precision)
+func FromDateHeader(sentAt, receivedAt time.Time, header http.Header) (Sample, error) {
+	date := header.Get("Date")
+	if date == "" {
+		return Sample{}, fmt.Errorf("no Date header in response")
+	}
+	serverTime, err := http.ParseTime(date)
+	if err != nil {
+		return Sample{}, fmt.Errorf("failed to parse Date header: %w", err)
+	}
+	return NewSample(sentAt, receivedAt, serverTime, time.Second), nil
+}
+
+// HTTPSample measures a server's clock from the Date header of a GET request
+func HTTPSample(client *http.Client, req *http.Request) (Sample, error) {
+	sentAt := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return Sample{}, fmt.Errorf("request failed: %w", err)
+	}
+	receivedAt := time.Now()
+	resp.Body.Close()
+	return FromDateHeader(sentAt, receivedAt, resp.Header)
+}
+
+// BitgetSample measures Bitget's clock with the public time endpoint (1ms precision)
+func BitgetSample(client *http.Client, baseURL string) (Sample, error) {
+	sentAt := time.Now()
+	resp, err := client.Get(baseURL + "/api/v2/public/time")
+	if err != nil {
+		return Sample{}, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	receivedAt := time.Now()
+
+	var result struct {
+		Code string `json:"code"`
+		Msg  string `json:"msg"`
+		Data struct {
+			ServerTime string `json:"serverTime"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return Sample{}, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if result.Code != "00000" {
+		return Sample{}, fmt.Errorf("server error: %s", result.Msg)
+	}
+	serverMs, err := strconv.ParseInt(result.Data.ServerTime, 10, 64)
+	if err != nil {
+		return Sample{}, fmt.Errorf("failed to parse server time: %w", err)
+	}
+	return NewSample(sentAt, receivedAt, time.UnixMilli(serverMs), time.Millisecond), nil
+}
+
+// Estimate is the filtered offset of a tracker
+type Estimate struct {
+	Offset      time.Duration // Midpoint of the combined bounds
+	Uncertainty time.Duration // Half-width of the combined bounds
+	Samples     int           // Samples that agree on the bounds
+	UpdatedAt   time.Time
+}
+
+// Tracker keeps a window of samples from one source and combines their bounds
+type Tracker struct {
+	Name   string
+	source func() (Sample, error)
+	window int
+
+	mu       sync.RWMutex
+	samples  []Sample
+	estimate Estimate
+}
+
+// NewTracker tracks the clock behind source, combining up to window recent samples
+func NewTracker(name string, source func() (Sample, error), window int) *Tracker {
+	if window < 1 {
+		window = 1
+	}
+	return &Tracker{Name: name, source: source, window: window}
+}
+
+// Sample takes one measurement and folds it into the estimate
+func (t *Tracker) Sample() (Sample, Estimate, error) {
+	sample, err := t.source()
+	if err != nil {
+		return Sample{}, t.Estimate(), err
+	}
+	return sample, t.Add(sample), nil
+}
+
+// Add folds a sample into the estimate. When the newest sample contradicts older ones
+// (the local clock was stepped or drifted), the oldest samples are dropped until they agree.
+func (t *Tracker) Add(sample Sample) Estimate {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.samples = append(t.samples, sample)
+	if len(t.samples) > t.window {
+		t.samples = t.samples[len(t.samples)-t.window:]
+	}
+
+	for {
+		lo, hi := t.samples[0].MinOffset, t.samples[0].MaxOffset
+		for _, s := range t.samples[1:] {
+			lo, hi = max(lo, s.MinOffset), min(hi, s.MaxOffset)
+		}
+		if lo <= hi || len(t.samples) == 1 {
+			t.estimate = Estimate{
+				Offset:      (lo + hi) / 2,
+				Uncertainty: (hi - lo) / 2,
+				Samples:     len(t.samples),
+				UpdatedAt:   sample.LocalTime,
+			}
+			return t.estimate
+		}
+		t.samples = t.samples[1:]
+	}
+}
+
+// Estimate returns the current filtered offset (zero before the first sample)
+func (t *Tracker) Estimate() Estimate {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.estimate
+}
+
+// Now returns the local clock corrected to the server's clock
+func (t *Tracker) Now() time.Time {
+	return time.Now().Add(t.Estimate().Offset)
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Entry modes (UserData.EntryMode)
+const (
+	EntryMarket = "market" // Market order (default)
+	EntryIOC    = "ioc"    // Limit at last price + max slippage, unfilled part cancelled
+	EntryFOK    = "fok"    // Same limit, filled completely or not at all
+	EntryTWAP   = "twap"   // Slices over a short window, aborted past the price cap (twap_entry.go)
+
+	defaultMaxSlippagePct = 1.0
+	maxEntryRetries       = 5
+	orderDetailPolls      = 5
+	orderDetailPollDelay  = 100 * time.Millisecond
+)
+
+// EntryParams controls how OpenLongPosition enters
+type EntryParams struct {
+	Mode           string
+	MaxSlippagePct float64       // Limit price cap above the first quoted last price (fixed across re-quotes)
+	Retries        int           // Re-quotes after an attempt leaves size unfilled
+	Slices         int           // TWAP child orders
+	Window         time.Duration // TWAP window from first to last slice
+}
+
+// entryParams returns the user's entry settings, defaulting to market
+func (u *UserData) entryParams() EntryParams {
+	params := EntryParams{
+		Mode:           u.EntryMode,
+		MaxSlippagePct: u.MaxSlippagePct,
+		Retries:        u.EntryRetries,
+		Slices:         u.TWAPSlices,
+		Window:         time.Duration(u.TWAPWindowSec) * time.Second,
+	}
+	if params.Mode == "" {
+		params.Mode = EntryMarket
+	}
+	if params.MaxSlippagePct <= 0 {
+		params.MaxSlippagePct = defaultMaxSlippagePct
+	}
+	if params.Slices <= 0 {
+		params.Slices = defaultTWAPSlices
+	}
+	if params.Window <= 0 {
+		params.Window = defaultTWAPWindow
+	}
+	return params
+}
+
+// formatEntryMode renders the entry settings for the filters screen
+func (u *UserData) formatEntryMode() string {
+	params := u.entryParams()
+	switch params.Mode {
+	case EntryMarket:
+		return "piyasa emri"
+	case EntryTWAP:
+		return fmt.Sprintf("TWAP, %d parça / %v, fiyat sınırı %%%.2f", params.Slices, params.Window, params.MaxSlippagePct)
+	}
+	return fmt.Sprintf("%s limit, en fazla %%%.2f kayma, %d yeniden deneme",
+		strings.ToUpper(params.Mode), params.MaxSlippagePct, params.Retries)
+}
+
+// parseEntryArgs parses "/entry market|ioc|fok [slippage%] [retries]" or "/entry twap [slices] [seconds] [cap%]"
+func parseEntryArgs(args string) (EntryParams, error) {
+	fields := strings.Fields(strings.ToLower(args))
+	if len(fields) == 0 {
+		return EntryParams{}, fmt.Errorf("giriş modu eksik")
+	}
+
+	params := EntryParams{Mode: fields[0]}
+	switch params.Mode {
+	case EntryMarket:
+		if len(fields) > 1 {
+			return EntryParams{}, fmt.Errorf("market modu parametre almaz")
+		}
+		return params, nil
+	case EntryTWAP:
+		return parseTWAPArgs(fields[1:])
+	case EntryIOC, EntryFOK:
+	default:
+		return EntryParams{}, fmt.Errorf("bilinmeyen mod %q", fields[0])
+	}
+
+	if len(fields) > 1 {
+		slippage, err := strconv.ParseFloat(strings.TrimSuffix(fields[1], "%"), 64)
+		if err != nil || slippage <= 0 || slippage > 50 {
+			return EntryParams{}, fmt.Errorf("kayma 0-50 arasında olmalı")
+		}
+		params.MaxSlippagePct = slippage
+	}
+	if len(fields) > 2 {
+		retries, err := strconv.Atoi(fields[2])
+		if err != nil || retries < 0 || retries > maxEntryRetries {
+			return EntryParams{}, fmt.Errorf("yeniden deneme 0-%d arasında olmalı", maxEntryRetries)
+		}
+		params.Retries = retries
+	}
+	if len(fields) > 3 {
+		return EntryParams{}, fmt.Errorf("fazla parametre")
+	}
+	return params, nil
+}
+
+// contractPrecision returns price/size decimals and the minimum size for a contract
+func (b *BitgetAPI) contractPrecision(symbol string) (priceDecimals, sizeDecimals int, minSize float64, err error) {
+	contract, ok := symbolMapper.Contract(symbol)
+	if !ok {
+		contracts, err := b.GetContracts(symbol)
+		if err != nil || len(contracts) == 0 {
+			return 0, 0, 0, fmt.Errorf("no contract config for %s: %v", symbol, err)
+		}
+		contract = contracts[0]
+	}
+	priceDecimals, errPrice := strconv.Atoi(contract.PricePlace)
+	sizeDecimals, errSize := strconv.Atoi(contract.VolumePlace)
+	if errPrice != nil || errSize != nil {
+		return 0, 0, 0, fmt.Errorf("invalid precision for %s (price %q, size %q)", symbol, contract.PricePlace, contract.VolumePlace)
+	}
+	minSize, _ = strconv.ParseFloat(contract.MinTradeNum, 64)
+	return priceDecimals, sizeDecimals, minSize, nil
+}
+
+// floorTo rounds value down to the given number of decimals
+func floorTo(value float64, decimals int) float64 {
+	scale := math.Pow10(decimals)
+	return math.Floor(value*scale+1e-9) / scale
+}
+
+// openLongLimit enters with IOC/FOK limit orders, re-quoting up to Retries times while size is left
+// unfilled. Every limit stays at or below the first quote + MaxSlippagePct, so re-quotes never move
+// the cap; the response carries the real average fill
+func (b *BitgetAPI) openLongLimit(symbol string, quote, targetSize, marginUSDT float64, leverage int, entry EntryParams) (*OrderResponse, error) {
+	priceDecimals, sizeDecimals, minSize, err := b.contractPrecision(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &OrderResponse{
+		Symbol:        symbol,
+		Leverage:      leverage,
+		EntryMode:     entry.Mode,
+		QuotePrice:    quote,
+		RequestedSize: targetSize,
+	}
+	capPrice := floorTo(quote*(1+entry.MaxSlippagePct/100), priceDecimals)
+	filled, cost := 0.0, 0.0
+	var lastErr error
+
+	for attempt := 0; attempt <= entry.Retries; attempt++ {
+		if attempt > 0 {
+			if quote, err = b.GetSymbolPrice(symbol); err != nil {
+				lastErr = fmt.Errorf("re-quote failed: %w", err)
+				break
+			}
+			if quote > capPrice {
+				lastErr = fmt.Errorf("price %.8g ran past the %.8g cap", quote, capPrice)
+				break
+			}
+		}
+		remaining := floorTo(targetSize-filled, sizeDecimals)
+		if remaining <= 0 || remaining < minSize {
+			break
+		}
+		limit := math.Min(floorTo(quote*(1+entry.MaxSlippagePct/100), priceDecimals), capPrice)
+
+		resp.Attempts++
+		log.Printf("🎯 %s %s entry attempt %d: %s @ ≤ %s (quote %.8g)", symbol, strings.ToUpper(entry.Mode), resp.Attempts,
+			strconv.FormatFloat(remaining, 'f', sizeDecimals, 64), strconv.FormatFloat(limit, 'f', priceDecimals, 64), quote)
+		order, err := b.PlaceLimitOrder(symbol, OrderSideBuy,
+			strconv.FormatFloat(remaining, 'f', sizeDecimals, 64),
+			strconv.FormatFloat(limit, 'f', priceDecimals, 64),
+			entry.Mode)
+		if err != nil {
+			lastErr = err
+			break
+		}
+		if resp.OrderID == "" {
+			resp.OrderID = order.OrderID
+		}
+
+		// Fills are counted even when the order couldn't be settled, but then retrying stops:
+		// another order could double the position
+		detail, err := b.waitOrderFinal(symbol, order.OrderID)
+		if detail != nil && detail.Filled() > 0 {
+			got := detail.Filled()
+			filled += got
+			cost += got * detail.AvgPrice()
+			resp.FeeUSDT += detail.FeeUSDT()
+			resp.FilledAt = detail.FilledAt()
+			resp.OrderID = order.OrderID // Most recent fill, used to close/track the position
+		}
+		if err != nil {
+			lastErr = fmt.Errorf("order %s not settled: %w", order.OrderID, err)
+			break
+		}
+	}
+
+	if filled == 0 {
+		if lastErr != nil {
+			return nil, lastErr
+		}
+		return nil, fmt.Errorf("no fill within %.2f%% of last price after %d attempts", entry.MaxSlippagePct, resp.Attempts)
+	}
+
+	resp.Size = filled
+	resp.OpenPrice = cost / filled
+	resp.FillConfirmed = true
+	resp.UnfilledSize = floorTo(max(0, targetSize-filled), sizeDecimals)
+	resp.MarginUSDT = marginUSDT * filled / targetSize // Margin actually committed
+	if lastErr != nil {
+		log.Printf("⚠️ %s entry stopped after partial fill: %v", symbol, lastErr)
+	}
+	log.Printf("✅ %s filled %.8g/%.8g at avg %.8g (quote %.8g, slippage %.2f%%, %d attempts)",
+		symbol, filled, targetSize, resp.OpenPrice, resp.QuotePrice, resp.SlippagePct(), resp.Attempts)
+	return resp, nil
+}
+
+// waitOrderFinal polls a limit order until it can no longer fill (IOC/FOK settle within ms).
+// An order still live after polling is cancelled and re-read so the fills returned are final;
+// when that fails the error comes with the last detail read (nil if none), whose fills may grow.
+func (b *BitgetAPI) waitOrderFinal(symbol, orderID string) (*OrderDetail, error) {
+	detail, err := b.pollOrderDetail(symbol, orderID)
+	if err == nil && detail.Final() {
+		return detail, nil
+	}
+
+	log.Printf("⚠️ %s order %s not final after %d polls, cancelling", symbol, orderID, orderDetailPolls)
+	cancelErr := b.CancelOrder(symbol, orderID)
+	// Re-read even when the cancel failed: the order may have finished in between
+	settled, readErr := b.pollOrderDetail(symbol, orderID)
+	if readErr == nil && settled.Final() {
+		return settled, nil
+	}
+	if settled == nil {
+		settled = detail
+	}
+	switch {
+	case cancelErr != nil:
+		return settled, fmt.Errorf("order still live: %w", cancelErr)
+	case readErr != nil:
+		return settled, fmt.Errorf("order cancelled but not re-read: %w", readErr)
+	}
+	return settled, fmt.Errorf("order still %s after cancel", settled.State)
+}
+
+// pollOrderDetail reads the order up to orderDetailPolls times until it is final; it returns the
+// last successful read (final or not), or the error when every read failed
+func (b *BitgetAPI) pollOrderDetail(symbol, orderID string) (*OrderDetail, error) {
+	var last *OrderDetail
+	var err error
+	for i := 0; i < orderDetailPolls; i++ {
+		var detail *OrderDetail
+		if detail, err = b.GetOrderDetail(symbol, orderID); err == nil {
+			last = detail
+			if detail.Final() {
+				break
+			}
+		}
+		time.Sleep(orderDetailPollDelay)
+	}
+	if last == nil {
+		return nil, err
+	}
+	return last, nil
+}
+
+// SlippagePct is the average fill vs the quoted last price
+func (r *OrderResponse) SlippagePct() float64 {
+	if r.QuotePrice <= 0 || r.OpenPrice <= 0 {
+		return 0
+	}
+	return (r.OpenPrice/r.QuotePrice - 1) * 100
+}
+
+// formatEntryExecution renders the fill report for the position notification ("" if nothing to add)
+func formatEntryExecution(r *OrderResponse) string {
+	if r.QuotePrice <= 0 {
+		return ""
+	}
+	var b strings.Builder
+	if !r.FillConfirmed {
+		fmt.Fprintf(&b, "\n🎯 Giriş: %s, dolum fiyatı henüz alınamadı (teklif $%.4f gösteriliyor)",
+			strings.ToUpper(r.EntryMode), r.QuotePrice)
+		return b.String()
+	}
+	fmt.Fprintf(&b, "\n🎯 Giriş: %s, ortalama dolum $%.4f (teklif $%.4f, kayma %%%.2f)",
+		strings.ToUpper(r.EntryMode), r.OpenPrice, r.QuotePrice, r.SlippagePct())
+	switch {
+	case r.Slices > 0:
+		fmt.Fprintf(&b, ", %d/%d parça doldu", len(r.ChildOrderIDs), r.Slices)
+	case r.Attempts > 1:
+		fmt.Fprintf(&b, ", %d deneme", r.Attempts)
+	}
+	if r.AbortReason != "" {
+		fmt.Fprintf(&b, "\n⛔ Erken durduruldu: %s", r.AbortReason)
+	}
+	if r.FeeUSDT > 0 {
+		fmt.Fprintf(&b, "\n🧾 Komisyon: %.4f USDT", r.FeeUSDT)
+	}
+	if r.UnfilledSize > 0 {
+		fmt.Fprintf(&b, "\n⚠️ Dolmayan miktar: %.8f / %.8f", r.UnfilledSize, r.RequestedSize)
+	}
+	return b.String()
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseEntryArgs(t *testing.T) {
+	tests := []struct {
+		args    string
+		want    EntryParams
+		wantErr bool
+	}{
+		{"market", EntryParams{Mode: EntryMarket}, false},
+		{"MARKET", EntryParams{Mode: EntryMarket}, false},
+		{"ioc", EntryParams{Mode: EntryIOC}, false},
+		{"ioc 0.5", EntryParams{Mode: EntryIOC, MaxSlippagePct: 0.5}, false},
+		{"fok 2% 3", EntryParams{Mode: EntryFOK, MaxSlippagePct: 2, Retries: 3}, false},
+		{"ioc 1 0", EntryParams{Mode: EntryIOC, MaxSlippagePct: 1}, false},
+		{"twap 4 10 1.5", EntryParams{Mode: EntryTWAP, Slices: 4, Window: 10 * time.Second, MaxSlippagePct: 1.5}, false},
+		{"", EntryParams{}, true},
+		{"limit", EntryParams{}, true},
+		{"market 1", EntryParams{}, true},
+		{"ioc 0", EntryParams{}, true},
+		{"ioc -1", EntryParams{}, true},
+		{"ioc 51", EntryParams{}, true},
+		{"ioc abc", EntryParams{}, true},
+		{fmt.Sprintf("fok 1 %d", maxEntryRetries+1), EntryParams{}, true},
+		{"fok 1 -1", EntryParams{}, true},
+		{"fok 1 2 3", EntryParams{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseEntryArgs(tt.args)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseEntryArgs(%q) error = %v, wantErr %v", tt.args, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("parseEntryArgs(%q) = %+v, want %+v", tt.args, got, tt.want)
+		}
+	}
+}
+
+// fakeOrderServer answers order detail and cancel requests for one order
+type fakeOrderServer struct {
+	mu         sync.Mutex
+	state      string // Reported order state
+	filled     string
+	cancelFail bool
+	cancels    int
+}
+
+func (f *fakeOrderServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.URL.Path {
+	case "/api/v2/mix/order/detail":
+		fmt.Fprintf(w, `{"code":"00000","data":{"orderId":"1","state":%q,"baseVolume":%q,"priceAvg":"1.5"}}`, f.state, f.filled)
+	case "/api/v2/mix/order/cancel-order":
+		f.cancels++
+		if f.cancelFail {
+			fmt.Fprint(w, `{"code":"40034","msg":"system busy"}`)
+			return
+		}
+		f.state = "canceled"
+		fmt.Fprint(w, `{"code":"00000","data":{"orderId":"1"}}`)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newFakeBitget(t *testing.T, handler http.Handler) *BitgetAPI {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	api := NewBitgetAPI("key", "secret", "pass")
+	api.BaseURL = server.URL
+	api.budget = &RateBudget{rate: 1000, burst: 1000, tokens: 1000, last: time.Now()}
+	return api
+}
+
+// withTestContracts serves contracts from the shared symbolMapper for the rest of the test
+func withTestContracts(t *testing.T, contracts map[string]ContractInfo) {
+	t.Helper()
+	symbolMapper.mu.Lock()
+	prevContracts, prevFetchedAt := symbolMapper.contracts, symbolMapper.fetchedAt
+	symbolMapper.contracts = contracts
+	symbolMapper.fetchedAt = time.Now()
+	symbolMapper.mu.Unlock()
+	t.Cleanup(func() {
+		symbolMapper.mu.Lock()
+		symbolMapper.contracts, symbolMapper.fetchedAt = prevContracts, prevFetchedAt
+		symbolMapper.mu.Unlock()
+	})
+}
+
+func TestWaitOrderFinal(t *testing.T) {
+	tests := []struct {
+		name        string
+		server      *fakeOrderServer
+		wantState   string
+		wantFilled  float64
+		wantCancels int
+		wantErr     string
+	}{
+		{"filled order is returned as is", &fakeOrderServer{state: "filled", filled: "10"}, "filled", 10, 0, ""},
+		{"live order is cancelled and re-read", &fakeOrderServer{state: "partially_filled", filled: "4"}, "canceled", 4, 1, ""},
+		{"failed cancel stops with the last fills", &fakeOrderServer{state: "partially_filled", filled: "4", cancelFail: true}, "partially_filled", 4, 1, "still live"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newFakeBitget(t, tt.server)
+
+			detail, err := api.waitOrderFinal("FOOUSDT", "1")
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+			if detail == nil || detail.State != tt.wantState || detail.Filled() != tt.wantFilled {
+				t.Fatalf("detail = %+v, want state %s with %v filled", detail, tt.wantState, tt.wantFilled)
+			}
+			if tt.server.cancels != tt.wantCancels {
+				t.Errorf("cancels = %d, want %d", tt.server.cancels, tt.wantCancels)
+			}
+		})
+	}
+}
+
+// A re-quote exactly at the first-quote cap is still tried; one past it stops the entry
+func TestOpenLongLimitRequoteAtCap(t *testing.T) {
+	withTestContracts(t, map[string]ContractInfo{"FOOUSDT": {Symbol: "FOOUSDT", PricePlace: "4", VolumePlace: "0", MinTradeNum: "1"}})
+
+	tests := []struct {
+		requote    string
+		wantPlaced int
+	}{
+		{"1.01", 2},
+		{"1.0101", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.requote, func(t *testing.T) {
+			orders := &fakeOrderServer{state: "canceled", filled: "4"}
+			placed := 0
+			api := newFakeBitget(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/api/v2/mix/order/place-order":
+					placed++
+					fmt.Fprint(w, `{"code":"00000","data":{"orderId":"1"}}`)
+				case "/api/v2/mix/market/ticker":
+					fmt.Fprintf(w, `{"code":"00000","data":[{"lastPr":%q}]}`, tt.requote)
+				default:
+					orders.ServeHTTP(w, r)
+				}
+			}))
+
+			_, err := api.openLongLimit("FOOUSDT", 1, 10, 10, 2, EntryParams{Mode: EntryIOC, MaxSlippagePct: 1, Retries: 1})
+			if err != nil {
+				t.Fatalf("openLongLimit: %v", err)
+			}
+			if placed != tt.wantPlaced {
+				t.Errorf("placed %d orders, want %d", placed, tt.wantPlaced)
+			}
+		})
+	}
+}
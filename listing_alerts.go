@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// alertSendInterval keeps alert bursts under Telegram's ~30 msg/s bot limit
+const alertSendInterval = 35 * time.Millisecond
+
+// loadAlertChatIDs parses ALERT_CHAT_IDS (comma-separated channel/group chat IDs)
+func loadAlertChatIDs() []int64 {
+	var chatIDs []int64
+	for _, part := range strings.Split(os.Getenv("ALERT_CHAT_IDS"), ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		chatID, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			log.Printf("⚠️ Invalid chat ID '%s' in ALERT_CHAT_IDS, skipping", part)
+			continue
+		}
+		chatIDs = append(chatIDs, chatID)
+	}
+	return chatIDs
+}
+
+// loadAlertTimezone returns the timezone mute windows are interpreted in (ALERT_TZ)
+func loadAlertTimezone() *time.Location {
+	tzName := os.Getenv("ALERT_TZ")
+	if tzName == "" {
+		tzName = "Europe/Istanbul" // Same default as the monitor pause schedule
+	}
+
+	location, err := time.LoadLocation(tzName)
+	if err != nil {
+		log.Printf("⚠️ Invalid ALERT_TZ '%s', using UTC", tzName)
+		return time.UTC
+	}
+	return location
+}
+
+// isAlertMuted reports whether now falls within the user's mute window
+func (u *UserData) isAlertMuted(now time.Time) bool {
+	if u.MuteStart == "" || u.MuteEnd == "" {
+		return false
+	}
+	start := parseTimeToMinutes(u.MuteStart, -1)
+	end := parseTimeToMinutes(u.MuteEnd, -1)
+	if start < 0 || end < 0 || start == end {
+		return false
+	}
+	return inMinuteWindow(now.Hour()*60+now.Minute(), start, end)
+}
+
+// formatListingAlert renders the instant detection alert
+func formatListingAlert(listing ListingInfo) string {
+	markets := "bilinmiyor"
+	if len(listing.Markets) > 0 {
+		markets = strings.Join(listing.Markets, ", ")
+	}
+
+	latency := "bilinmiyor"
+	if d := listing.DetectionLatency(); d > 0 {
+		latency = d.Round(time.Millisecond).String()
+	}
+
+	text := fmt.Sprintf(`🚨 YENİ UPBIT LİSTELEMESİ
+
+💹 Coin: %s
+🏦 Market: %s
+📰 Başlık: %s
+🗂️ Kategori: %s`, listing.Symbol, markets, listing.Title, listing.Category)
+
+	if link := listing.NoticeURL(); link != "" {
+		text += "\n🔗 " + link
+	}
+	text += "\n⏱️ Tespit gecikmesi: " + latency
+	if listing.Proxy != "" {
+		text += fmt.Sprintf("\n🛰️ %s (kural: %s)", listing.Proxy, listing.MatchedRule)
+	}
+
+	return text
+}
+
+// BroadcastListingAlert sends the detection alert to subscribers and alert channels
+func (tb *TelegramBot) BroadcastListingAlert(listing ListingInfo) {
+	text := formatListingAlert(listing)
+	now := time.Now().In(tb.alertTimezone)
+
+	var recipients []int64
+	tb.database.mutex.RLock()
+	for _, user := range tb.database.Users {
+		if !user.AlertSubscribed {
+			continue
+		}
+		if user.isAlertMuted(now) {
+			log.Printf("🔕 Alert for %s muted for user %d", listing.Symbol, user.UserID)
+			continue
+		}
+		recipients = append(recipients, user.UserID)
+	}
+	tb.database.mutex.RUnlock()
+
+	recipients = append(recipients, tb.alertChatIDs...)
+
+	for _, chatID := range recipients {
+		msg := tgbotapi.NewMessage(chatID, text)
+		msg.DisableWebPagePreview = true
+		if _, err := tb.bot.Send(msg); err != nil {
+			log.Printf("⚠️ Failed to send listing alert to %d: %v", chatID, err)
+		}
+		time.Sleep(alertSendInterval)
+	}
+
+	log.Printf("🔔 Listing alert for %s sent to %d chats", listing.Symbol, len(recipients))
+}
+
+// handleAlertsQuery shows the user's alert subscription and mute window
+func (tb *TelegramBot) handleAlertsQuery(chatID int64, userID int64) {
+	user, exists := tb.getUser(userID)
+	if !exists {
+		tb.sendMessage(chatID, "❌ Önce /start yazın.")
+		return
+	}
+
+	mute := "yok"
+	if user.MuteStart != "" && user.MuteEnd != "" {
+		mute = fmt.Sprintf("%s - %s (%s)", user.MuteStart, user.MuteEnd, tb.alertTimezone)
+	}
+
+	alertsMsg := fmt.Sprintf(`🔔 LİSTELEME ALARMI
+
+API anahtarı gerekmez: Upbit'te yeni listeleme tespit edildiği anda coin, orijinal başlık, duyuru linki ve tespit gecikmesi ile bildirim alırsınız.
+
+• Abonelik: %s
+• Sessiz saatler: %s
+
+✏️ KOMUTLAR:
+/subscribe - alarmı aç
+/unsubscribe - alarmı kapat
+/mute 23:00-07:00 - bu saatlerde bildirim gönderme
+/mute off - sessiz saatleri kaldır`,
+		map[bool]string{true: "🟢 Açık", false: "🔴 Kapalı"}[user.AlertSubscribed],
+		mute)
+
+	toggle := tgbotapi.NewInlineKeyboardButtonData("🔔 Alarmı Aç", "alerts_on")
+	if user.AlertSubscribed {
+		toggle = tgbotapi.NewInlineKeyboardButtonData("🔕 Alarmı Kapat", "alerts_off")
+	}
+
+	msg := tgbotapi.NewMessage(chatID, alertsMsg)
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(toggle),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("« Geri", "main_menu"),
+		),
+	)
+	tb.bot.Send(msg)
+}
+
+// handleAlertSubscription subscribes or unsubscribes the user, creating the user record if needed
+func (tb *TelegramBot) handleAlertSubscription(chatID int64, userID int64, username string, subscribed bool) {
+	user, exists := tb.getUser(userID)
+	if !exists {
+		user = &UserData{
+			UserID:   userID,
+			Username: username,
+			IsActive: false,
+			State:    StateNone,
+		}
+	}
+
+	user.AlertSubscribed = subscribed
+	if err := tb.saveUser(user); err != nil {
+		tb.sendMessage(chatID, fmt.Sprintf("❌ Ayar kaydedilemedi: %v", err))
+		return
+	}
+
+	log.Printf("🔔 User %d set alert subscription=%v", userID, subscribed)
+	tb.handleAlertsQuery(chatID, userID)
+}
+
+// handleMuteCommand sets the mute window from "/mute HH:MM-HH:MM" or clears it with "/mute off"
+func (tb *TelegramBot) handleMuteCommand(chatID int64, userID int64, args string) {
+	user, exists := tb.getUser(userID)
+	if !exists {
+		tb.sendMessage(chatID, "❌ Önce /start yazın.")
+		return
+	}
+
+	args = strings.TrimSpace(args)
+	if strings.EqualFold(args, "off") {
+		user.MuteStart, user.MuteEnd = "", ""
+	} else {
+		parts := strings.Split(args, "-")
+		if len(parts) != 2 {
+			tb.sendMessage(chatID, "❌ Kullanım: /mute 23:00-07:00 veya /mute off")
+			return
+		}
+		start, end := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		if parseTimeToMinutes(start, -1) < 0 || parseTimeToMinutes(end, -1) < 0 || start == end {
+			tb.sendMessage(chatID, "❌ Geçersiz saat! Kullanım: /mute 23:00-07:00")
+			return
+		}
+		user.MuteStart, user.MuteEnd = start, end
+	}
+
+	if err := tb.saveUser(user); err != nil {
+		tb.sendMessage(chatID, fmt.Sprintf("❌ Ayar kaydedilemedi: %v", err))
+		return
+	}
+
+	tb.handleAlertsQuery(chatID, userID)
+}
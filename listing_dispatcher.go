@@ -0,0 +1,645 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	json "github.com/json-iterator/go"
+)
+
+// defaultLedgerRetention is how long ledger entries block a repeat dispatch or trade;
+// override with LISTING_LEDGER_RETENTION_DAYS
+const defaultLedgerRetention = 30 * 24 * time.Hour
+
+// LedgerEntry is everything the dispatcher has done for one listing: a symbol's notice, or
+// its markets when a source has no notice ID. A relisted ticker or a later market for the
+// same coin comes with a new notice and gets a new entry.
+type LedgerEntry struct {
+	Key      string
+	Symbol   string
+	NoticeID int
+	Markets  []string
+	Created  time.Time
+	Sources  map[ListingSource]time.Time // First dispatch per source
+	Users    map[int64]time.Time         // Users whose trade (or approval request) was started
+}
+
+// Ledger file operations, one JSON record per line
+const (
+	ledgerOpListing  = "listing"  // New entry; replaces an expired entry with the same key
+	ledgerOpSource   = "source"   // Entry dispatched from a source
+	ledgerOpUser     = "user"     // User claimed for an entry
+	ledgerOpNegative = "negative" // User reacted to a delisting/caution notice (key from negativeKey)
+)
+
+// ledgerRecord is one line of the append-only ledger file
+type ledgerRecord struct {
+	Op       string        `json:"op"`
+	Key      string        `json:"key"`
+	Symbol   string        `json:"symbol,omitempty"`
+	NoticeID int           `json:"notice_id,omitempty"`
+	Markets  []string      `json:"markets,omitempty"`
+	Source   ListingSource `json:"source,omitempty"`
+	UserID   int64         `json:"user_id,omitempty"`
+	At       time.Time     `json:"at"`
+}
+
+// legacyLedgerEntry is the former whole-file JSON array format, keyed by symbol only
+type legacyLedgerEntry struct {
+	Symbol   string                      `json:"symbol"`
+	NoticeID int                         `json:"notice_id,omitempty"`
+	Sources  map[ListingSource]time.Time `json:"sources"`
+	Users    map[int64]time.Time         `json:"users,omitempty"`
+	Negative map[int]map[int64]time.Time `json:"negative,omitempty"`
+}
+
+// ListingDispatcher is the single entry point from listing sources to alerts and trading.
+// Every step is appended to its ledger before anything is started, so a listing is
+// dispatched at most once per source and traded at most once per user, across sources and
+// restarts. Entries older than the retention window are dropped when the ledger is loaded.
+type ListingDispatcher struct {
+	ledgerFile string
+	retention  time.Duration
+
+	mu       sync.Mutex
+	file     *os.File // Ledger opened for appending
+	entries  map[string]*LedgerEntry
+	negative map[string]map[int64]time.Time // negativeKey -> users whose reaction ran
+}
+
+// NewListingDispatcher loads the ledger from ledgerFile (missing file = empty ledger) and
+// compacts it to the entries still inside the retention window. An unreadable or corrupt
+// ledger is an error: starting empty could trade every recent listing a second time.
+func NewListingDispatcher(ledgerFile string) (*ListingDispatcher, error) {
+	ld := &ListingDispatcher{
+		ledgerFile: ledgerFile,
+		retention:  envDuration("LISTING_LEDGER_RETENTION_DAYS", 24*time.Hour, defaultLedgerRetention),
+		entries:    make(map[string]*LedgerEntry),
+		negative:   make(map[string]map[int64]time.Time),
+	}
+
+	data, err := ioutil.ReadFile(ledgerFile)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("listing ledger %s could not be read: %w", ledgerFile, err)
+	}
+	if err := ld.loadLocked(data); err != nil {
+		return nil, fmt.Errorf("listing ledger %s is corrupt, fix or move it before starting: %w", ledgerFile, err)
+	}
+	ld.pruneLocked(time.Now())
+	if err := ld.compactLocked(); err != nil {
+		return nil, err
+	}
+	if len(ld.entries) > 0 || len(ld.negative) > 0 {
+		log.Printf("📒 Loaded listing ledger with %d listings from %s", len(ld.entries), ledgerFile)
+	}
+	return ld, nil
+}
+
+// loadLocked replays ledger records. A torn last line (crash mid-append) is dropped; any
+// other unparseable line fails the load.
+func (ld *ListingDispatcher) loadLocked(data []byte) error {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil
+	}
+	if trimmed[0] == '[' {
+		return ld.loadLegacyLocked(trimmed)
+	}
+
+	lines := bytes.Split(data, []byte("\n"))
+	for i, line := range lines {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var record ledgerRecord
+		if err := json.Unmarshal(line, &record); err != nil || record.Op == "" || record.Key == "" {
+			if i == len(lines)-1 {
+				log.Printf("⚠️ Dropping torn last line of %s", ld.ledgerFile)
+				continue
+			}
+			if err == nil {
+				err = fmt.Errorf("missing op or key")
+			}
+			return fmt.Errorf("line %d: %w", i+1, err)
+		}
+		ld.applyLocked(record)
+	}
+	return nil
+}
+
+// loadLegacyLocked converts the former symbol-keyed array; its entries keep their notice ID
+func (ld *ListingDispatcher) loadLegacyLocked(data []byte) error {
+	var legacy []legacyLedgerEntry
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return err
+	}
+	for _, old := range legacy {
+		listing := ListingInfo{Symbol: old.Symbol, NoticeID: old.NoticeID}
+		created := time.Now()
+		for _, at := range old.Sources {
+			if at.Before(created) {
+				created = at
+			}
+		}
+		entry := newLedgerEntry(listing, created)
+		for source, at := range old.Sources {
+			entry.Sources[source] = at
+		}
+		for userID, at := range old.Users {
+			entry.Users[userID] = at
+		}
+		ld.entries[entry.Key] = entry
+		for noticeID, users := range old.Negative {
+			for userID, at := range users {
+				ld.applyLocked(ledgerRecord{Op: ledgerOpNegative, Key: negativeKey(old.Symbol, noticeID), UserID: userID, At: at})
+			}
+		}
+	}
+	log.Printf("📒 Converting %d symbols of %s to the append-only ledger", len(legacy), ld.ledgerFile)
+	return nil
+}
+
+func (ld *ListingDispatcher) applyLocked(record ledgerRecord) {
+	switch record.Op {
+	case ledgerOpListing:
+		ld.entries[record.Key] = &LedgerEntry{
+			Key:      record.Key,
+			Symbol:   record.Symbol,
+			NoticeID: record.NoticeID,
+			Markets:  record.Markets,
+			Created:  record.At,
+			Sources:  make(map[ListingSource]time.Time),
+			Users:    make(map[int64]time.Time),
+		}
+	case ledgerOpSource:
+		if entry := ld.entries[record.Key]; entry != nil {
+			if _, seen := entry.Sources[record.Source]; !seen {
+				entry.Sources[record.Source] = record.At
+			}
+		}
+	case ledgerOpUser:
+		if entry := ld.entries[record.Key]; entry != nil {
+			entry.Users[record.UserID] = record.At
+		}
+	case ledgerOpNegative:
+		users := ld.negative[record.Key]
+		if users == nil {
+			users = make(map[int64]time.Time)
+			ld.negative[record.Key] = users
+		}
+		users[record.UserID] = record.At
+	}
+}
+
+// pruneLocked drops entries and negative claims older than the retention window
+func (ld *ListingDispatcher) pruneLocked(now time.Time) {
+	for key, entry := range ld.entries {
+		if now.Sub(entry.Created) > ld.retention {
+			delete(ld.entries, key)
+		}
+	}
+	for key, users := range ld.negative {
+		for userID, at := range users {
+			if now.Sub(at) > ld.retention {
+				delete(users, userID)
+			}
+		}
+		if len(users) == 0 {
+			delete(ld.negative, key)
+		}
+	}
+}
+
+// compactLocked rewrites the ledger with only the live records via a temp file, so a crash
+// never leaves it truncated, then reopens it for appending
+func (ld *ListingDispatcher) compactLocked() error {
+	entries := make([]*LedgerEntry, 0, len(ld.entries))
+	for _, entry := range ld.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Created.Before(entries[j].Created) })
+
+	var records []ledgerRecord
+	for _, entry := range entries {
+		records = append(records, entry.listingRecord())
+		for source, at := range entry.Sources {
+			records = append(records, ledgerRecord{Op: ledgerOpSource, Key: entry.Key, Source: source, At: at})
+		}
+		for userID, at := range entry.Users {
+			records = append(records, ledgerRecord{Op: ledgerOpUser, Key: entry.Key, UserID: userID, At: at})
+		}
+	}
+	for key, users := range ld.negative {
+		for userID, at := range users {
+			records = append(records, ledgerRecord{Op: ledgerOpNegative, Key: key, UserID: userID, At: at})
+		}
+	}
+
+	data, err := encodeLedgerRecords(records)
+	if err != nil {
+		return err
+	}
+	tmp := ld.ledgerFile + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, ld.ledgerFile); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", ld.ledgerFile, err)
+	}
+
+	file, err := os.OpenFile(ld.ledgerFile, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", ld.ledgerFile, err)
+	}
+	ld.file = file
+	return nil
+}
+
+// appendLocked writes records in a single append, so the hot path never rewrites the file
+func (ld *ListingDispatcher) appendLocked(records ...ledgerRecord) error {
+	data, err := encodeLedgerRecords(records)
+	if err != nil {
+		return err
+	}
+	if _, err := ld.file.Write(data); err != nil {
+		return fmt.Errorf("failed to append to %s: %w", ld.ledgerFile, err)
+	}
+	return nil
+}
+
+func encodeLedgerRecords(records []ledgerRecord) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, record := range records {
+		line, err := json.Marshal(record)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal listing ledger: %w", err)
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// ledgerKey names a new entry: the notice when known, otherwise the symbol's markets
+func ledgerKey(listing ListingInfo) string {
+	if listing.NoticeID != 0 {
+		return fmt.Sprintf("%s#%d", listing.Symbol, listing.NoticeID)
+	}
+	markets := append([]string(nil), listing.Markets...)
+	sort.Strings(markets)
+	return listing.Symbol + "@" + strings.Join(markets, ",")
+}
+
+func negativeKey(symbol string, noticeID int) string {
+	return fmt.Sprintf("%s#%d", symbol, noticeID)
+}
+
+func newLedgerEntry(listing ListingInfo, created time.Time) *LedgerEntry {
+	return &LedgerEntry{
+		Key:      ledgerKey(listing),
+		Symbol:   listing.Symbol,
+		NoticeID: listing.NoticeID,
+		Markets:  listing.Markets,
+		Created:  created,
+		Sources:  make(map[ListingSource]time.Time),
+		Users:    make(map[int64]time.Time),
+	}
+}
+
+func (entry *LedgerEntry) listingRecord() ledgerRecord {
+	return ledgerRecord{Op: ledgerOpListing, Key: entry.Key, Symbol: entry.Symbol, NoticeID: entry.NoticeID, Markets: entry.Markets, At: entry.Created}
+}
+
+// matches reports whether a listing is this entry's: same notice when both have one,
+// otherwise the same markets (a source that doesn't know the markets matches any)
+func (entry *LedgerEntry) matches(listing ListingInfo) bool {
+	if entry.Symbol != listing.Symbol {
+		return false
+	}
+	if entry.NoticeID != 0 && listing.NoticeID != 0 {
+		return entry.NoticeID == listing.NoticeID
+	}
+	if len(entry.Markets) == 0 || len(listing.Markets) == 0 {
+		return true
+	}
+	return ledgerKey(ListingInfo{Symbol: entry.Symbol, Markets: entry.Markets}) ==
+		ledgerKey(ListingInfo{Symbol: listing.Symbol, Markets: listing.Markets})
+}
+
+// findLocked returns the live entry of a listing, nil if there is none
+func (ld *ListingDispatcher) findLocked(listing ListingInfo) *LedgerEntry {
+	now := time.Now()
+	for _, entry := range ld.entries {
+		if now.Sub(entry.Created) <= ld.retention && entry.matches(listing) {
+			return entry
+		}
+	}
+	return nil
+}
+
+// Begin records a listing from its source; false means this (listing, source) was already
+// dispatched. alert reports whether this is the listing's first dispatch from any source.
+func (ld *ListingDispatcher) Begin(listing ListingInfo) (ok bool, alert bool) {
+	ld.mu.Lock()
+	defer ld.mu.Unlock()
+
+	now := time.Now()
+	var records []ledgerRecord
+	entry := ld.findLocked(listing)
+	if entry == nil {
+		entry = newLedgerEntry(listing, now)
+		ld.entries[entry.Key] = entry
+		records = append(records, entry.listingRecord())
+	}
+	if _, seen := entry.Sources[listing.Source]; seen {
+		return false, false
+	}
+
+	alert = len(entry.Sources) == 0
+	entry.Sources[listing.Source] = now
+	if listing.Canary {
+		// Canary entries stay in memory and replace the previous run's, so the file doesn't grow
+		for key, other := range ld.entries {
+			if other != entry && isCanarySymbol(other.Symbol) {
+				delete(ld.entries, key)
+			}
+		}
+		return true, alert
+	}
+	records = append(records, ledgerRecord{Op: ledgerOpSource, Key: entry.Key, Source: listing.Source, At: now})
+	if err := ld.appendLocked(records...); err != nil {
+		log.Printf("❌ %v", err)
+	}
+	return true, alert
+}
+
+// ClaimUsers marks users as traded for a listing and returns the ones not claimed before
+func (ld *ListingDispatcher) ClaimUsers(listing ListingInfo, users []*UserData) []*UserData {
+	ld.mu.Lock()
+	defer ld.mu.Unlock()
+
+	entry := ld.findLocked(listing)
+	if entry == nil {
+		return nil // Begin was not called; refuse rather than trade unrecorded
+	}
+
+	var claimed []*UserData
+	var records []ledgerRecord
+	now := time.Now()
+	for _, user := range users {
+		if _, done := entry.Users[user.UserID]; done {
+			log.Printf("🔄 User %d already handled %s, skipping", user.UserID, entry.Key)
+			continue
+		}
+		entry.Users[user.UserID] = now
+		claimed = append(claimed, user)
+		records = append(records, ledgerRecord{Op: ledgerOpUser, Key: entry.Key, UserID: user.UserID, At: now})
+	}
+	if len(records) > 0 && !listing.Canary {
+		if err := ld.appendLocked(records...); err != nil {
+			log.Printf("❌ %v", err)
+		}
+	}
+	return claimed
+}
+
+// ClaimNegative records that a user's reaction to a delisting/caution notice for symbol is
+// done (longs closed or short opened); false means it already ran, before a restart too, so a
+// short is never opened twice
+func (ld *ListingDispatcher) ClaimNegative(symbol string, noticeID int, userID int64) bool {
+	ld.mu.Lock()
+	defer ld.mu.Unlock()
+
+	key := negativeKey(symbol, noticeID)
+	if _, done := ld.negative[key][userID]; done {
+		log.Printf("🔄 User %d already reacted to notice #%d for %s, skipping", userID, noticeID, symbol)
+		return false
+	}
+
+	record := ledgerRecord{Op: ledgerOpNegative, Key: key, UserID: userID, At: time.Now()}
+	ld.applyLocked(record)
+	if err := ld.appendLocked(record); err != nil {
+		log.Printf("❌ %v", err)
+	}
+	return true
+}
+
+// NegativeHandled reports whether a user's reaction to a delisting/caution notice is recorded
+func (ld *ListingDispatcher) NegativeHandled(symbol string, noticeID int, userID int64) bool {
+	ld.mu.Lock()
+	defer ld.mu.Unlock()
+
+	_, done := ld.negative[negativeKey(symbol, noticeID)][userID]
+	return done
+}
+
+// Handled reports whether a user was already claimed for a listing
+func (ld *ListingDispatcher) Handled(listing ListingInfo, userID int64) bool {
+	ld.mu.Lock()
+	defer ld.mu.Unlock()
+
+	entry := ld.findLocked(listing)
+	if entry == nil {
+		return false
+	}
+	_, done := entry.Users[userID]
+	return done
+}
+
+// DispatchNotice sends the new listings of one notice from any source to alerts and
+// auto-trading, once; the tickers are traded together under each user's allocation policy
+func (tb *TelegramBot) DispatchNotice(listings []ListingInfo) {
+	// A synthetic canary notice runs the whole pipeline and reports back where orders
+	// would be placed; alerts, user messages and orders are skipped along the way
+	if len(listings) > 0 && listings[0].Canary {
+		defer pipelineCanary.Handle(listings)
+	}
+
+	var accepted []ListingInfo
+	for _, listing := range listings {
+		symbol := listing.Symbol
+		if isCanarySymbol(symbol) != listing.Canary {
+			log.Printf("🐤 Refusing to dispatch %s: canary symbol and flag disagree", symbol)
+			continue
+		}
+
+		ok, alert := tb.dispatcher.Begin(listing)
+		if !ok {
+			log.Printf("🔄 %s from %s already dispatched, skipping", symbol, listing.Source)
+			continue
+		}
+		log.Printf("⚡ DISPATCH - %s from %s", symbol, listing.Source)
+
+		// Detection-only subscribers and alert channels (no API keys needed)
+		if alert && !listing.Canary {
+			go tb.BroadcastListingAlert(listing)
+		}
+		accepted = append(accepted, listing)
+	}
+	if len(accepted) == 0 {
+		return
+	}
+
+	activeUsers := tb.getAllActiveUsers()
+	if len(activeUsers) == 0 {
+		log.Printf("⚠️  No active users found for auto-trading")
+		return
+	}
+
+	log.Printf("⚡ FAST TRACK: Executing trades for %d users on %d tickers", len(activeUsers), len(accepted))
+
+	// Paused, filtered and already-traded users are skipped
+	tb.tradeForEligibleUsers(accepted, activeUsers)
+}
+
+// defaultListingInjectionFile is watched when LISTING_INJECTION_FILE is not set
+const defaultListingInjectionFile = "listing_inject.json"
+
+// listingFileInjectionEnabled reports LISTING_FILE_INJECTION=true: lines other processes
+// append to the injection file are dispatched as file-source listings
+func listingFileInjectionEnabled() bool {
+	return os.Getenv("LISTING_FILE_INJECTION") == "true"
+}
+
+// listingInjectionFile returns LISTING_INJECTION_FILE or listing_inject.json. It must not be
+// upbit_new.json: the monitor appends its own detections there, which would come back as
+// file-source listings and be dispatched a second time.
+func listingInjectionFile() (string, error) {
+	path := os.Getenv("LISTING_INJECTION_FILE")
+	if path == "" {
+		path = defaultListingInjectionFile
+	}
+	if filepath.Clean(path) == filepath.Clean(upbitDetectionsFile) {
+		return "", fmt.Errorf("LISTING_INJECTION_FILE must differ from %s, which the monitor writes itself", upbitDetectionsFile)
+	}
+	return path, nil
+}
+
+// startFileWatcher dispatches entries appended to the injection file after startup
+func (tb *TelegramBot) startFileWatcher() {
+	injectFile, err := listingInjectionFile()
+	if err != nil {
+		log.Printf("❌ Listing injection disabled: %v", err)
+		return
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Printf("❌ Failed to create file watcher: %v", err)
+		return
+	}
+	defer watcher.Close()
+
+	// Create the file so it can be watched before the first detection
+	file, err := os.OpenFile(injectFile, os.O_CREATE|os.O_RDONLY, 0644)
+	if err != nil {
+		log.Printf("❌ Failed to open %s: %v", injectFile, err)
+		return
+	}
+	info, err := file.Stat()
+	file.Close()
+	if err != nil {
+		log.Printf("❌ Failed to stat %s: %v", injectFile, err)
+		return
+	}
+	if err := watcher.Add(injectFile); err != nil {
+		log.Printf("❌ Failed to watch %s: %v", injectFile, err)
+		return
+	}
+
+	// Existing entries are history; only lines appended from now on are injected
+	offset := info.Size()
+	log.Printf("👁️  Watching %s for injected listings (from offset %d)", injectFile, offset)
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				log.Printf("❌ File watcher events channel closed")
+				return
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Chmod) == 0 {
+				continue
+			}
+			var entries []ListingEntry
+			entries, offset = readAppendedListings(injectFile, offset)
+			for _, group := range groupByNotice(entries) {
+				tb.DispatchNotice(group)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				log.Printf("❌ File watcher error channel closed")
+				return
+			}
+			log.Printf("❌ File watcher error: %v", err)
+		}
+	}
+}
+
+// readAppendedListings parses complete JSONL lines after offset and returns the new offset
+func readAppendedListings(path string, offset int64) ([]ListingEntry, int64) {
+	file, err := os.Open(path)
+	if err != nil {
+		log.Printf("⚠️ Could not read %s: %v", path, err)
+		return nil, offset
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, offset
+	}
+	if info.Size() < offset {
+		// Truncated or replaced: skip what is there now rather than replay history
+		log.Printf("⚠️ %s shrank, resetting offset", path)
+		return nil, info.Size()
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return nil, offset
+	}
+
+	var entries []ListingEntry
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			break // Partial last line is picked up on the next write
+		}
+		offset += int64(len(line))
+
+		var entry ListingEntry
+		if err := json.Unmarshal(line, &entry); err != nil || entry.Symbol == "" {
+			if len(line) > 1 {
+				log.Printf("⚠️ Skipping unparseable line in %s: %v", path, err)
+			}
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, offset
+}
+
+// groupByNotice turns consecutive file entries of the same notice into one dispatch
+func groupByNotice(entries []ListingEntry) [][]ListingInfo {
+	var groups [][]ListingInfo
+	for i, entry := range entries {
+		listing := entry.toListingInfo(ListingSourceFile)
+		if i > 0 && entry.NoticeID != 0 && entry.NoticeID == entries[i-1].NoticeID {
+			groups[len(groups)-1] = append(groups[len(groups)-1], listing)
+			continue
+		}
+		groups = append(groups, []ListingInfo{listing})
+	}
+	return groups
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestListingInjectionFileRefusesMonitorOutput(t *testing.T) {
+	tests := []struct {
+		env     string
+		want    string
+		wantErr bool
+	}{
+		{"", defaultListingInjectionFile, false},
+		{"inject/extra.json", "inject/extra.json", false},
+		{upbitDetectionsFile, "", true},
+		{"./" + upbitDetectionsFile, "", true},
+	}
+	for _, tt := range tests {
+		t.Setenv("LISTING_INJECTION_FILE", tt.env)
+		got, err := listingInjectionFile()
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("LISTING_INJECTION_FILE=%q: got %q, %v", tt.env, got, err)
+		}
+	}
+}
+
+func TestReadAppendedListings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "inject.json")
+	history := `{"symbol":"OLD","notice_id":1}` + "\n"
+	if err := os.WriteFile(path, []byte(history), 0644); err != nil {
+		t.Fatal(err)
+	}
+	offset := int64(len(history))
+
+	appended := `{"symbol":"AAA","notice_id":7}` + "\n" + `not json` + "\n" + `{"symbol":"BBB","notice_id":7}` + "\n" + `{"symbol":"CC`
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString(appended)
+	f.Close()
+
+	entries, next := readAppendedListings(path, offset)
+	if len(entries) != 2 || entries[0].Symbol != "AAA" || entries[1].Symbol != "BBB" {
+		t.Fatalf("entries = %+v, want AAA and BBB", entries)
+	}
+	if groups := groupByNotice(entries); len(groups) != 1 || len(groups[0]) != 2 {
+		t.Errorf("same-notice entries should form one group, got %d groups", len(groups))
+	}
+
+	// The partial line is read once it is completed
+	f, _ = os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString(`C"}` + "\n")
+	f.Close()
+	entries, _ = readAppendedListings(path, next)
+	if len(entries) != 1 || entries[0].Symbol != "CCC" {
+		t.Errorf("entries after completing the line = %+v, want CCC", entries)
+	}
+}
+
+func newTestListingDispatcher(t *testing.T, file string) *ListingDispatcher {
+	t.Helper()
+	ld, err := NewListingDispatcher(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ld
+}
+
+func TestClaimNegativeSurvivesRestart(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "ledger.json")
+
+	ld := newTestListingDispatcher(t, file)
+	if ld.NegativeHandled("ABC", 42, 1) {
+		t.Fatal("unclaimed notice reported handled")
+	}
+	if !ld.ClaimNegative("ABC", 42, 1) {
+		t.Fatal("first claim refused")
+	}
+	if ld.ClaimNegative("ABC", 42, 1) {
+		t.Error("second claim for the same notice and user accepted")
+	}
+	if !ld.ClaimNegative("ABC", 42, 2) || !ld.ClaimNegative("ABC", 43, 1) {
+		t.Error("another user or notice was refused")
+	}
+
+	restarted := newTestListingDispatcher(t, file)
+	if !restarted.NegativeHandled("ABC", 42, 1) {
+		t.Error("claim not reported handled after restart")
+	}
+	if restarted.ClaimNegative("ABC", 42, 1) {
+		t.Error("claim accepted again after restart")
+	}
+	// A later listing of the symbol is still dispatched normally
+	if ok, alert := restarted.Begin(ListingInfo{Symbol: "ABC", Source: ListingSourceUpbit}); !ok || !alert {
+		t.Errorf("Begin after negative claim = %v, %v; want true, true", ok, alert)
+	}
+}
+
+func TestLedgerClaimsUsersPerListing(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "ledger.json")
+	user := []*UserData{{UserID: 1}}
+	btc := ListingInfo{Symbol: "FOO", Source: ListingSourceUpbit, NoticeID: 10, Markets: []string{"BTC"}}
+
+	ld := newTestListingDispatcher(t, file)
+	if ok, alert := ld.Begin(btc); !ok || !alert {
+		t.Fatalf("first Begin = %v, %v; want true, true", ok, alert)
+	}
+	if len(ld.ClaimUsers(btc, user)) != 1 {
+		t.Fatal("first claim refused")
+	}
+
+	// The same notice from a source without notice IDs is the same listing
+	fromFile := ListingInfo{Symbol: "FOO", Source: ListingSourceFile, Markets: []string{"BTC"}}
+	if ok, alert := ld.Begin(fromFile); !ok || alert {
+		t.Errorf("Begin from second source = %v, %v; want true, false", ok, alert)
+	}
+	if len(ld.ClaimUsers(fromFile, user)) != 0 {
+		t.Error("user claimed twice for the same listing")
+	}
+
+	restarted := newTestListingDispatcher(t, file)
+	if ok, _ := restarted.Begin(btc); ok {
+		t.Error("listing dispatched again after restart")
+	}
+	if !restarted.Handled(btc, 1) {
+		t.Error("user claim lost on restart")
+	}
+
+	// A later KRW market notice for the coin is a new listing
+	krw := ListingInfo{Symbol: "FOO", Source: ListingSourceUpbit, NoticeID: 11, Markets: []string{"KRW"}}
+	if ok, alert := restarted.Begin(krw); !ok || !alert {
+		t.Errorf("Begin for KRW notice = %v, %v; want true, true", ok, alert)
+	}
+	if restarted.Handled(krw, 1) || len(restarted.ClaimUsers(krw, user)) != 1 {
+		t.Error("user not claimable for the KRW listing")
+	}
+}
+
+func TestLedgerDropsExpiredEntries(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "ledger.json")
+	old := time.Now().Add(-defaultLedgerRetention - time.Hour).Format(time.RFC3339)
+	recent := time.Now().Add(-time.Hour).Format(time.RFC3339)
+	ledger := `{"op":"listing","key":"OLD#1","symbol":"OLD","notice_id":1,"at":"` + old + `"}
+{"op":"source","key":"OLD#1","source":"upbit","at":"` + old + `"}
+{"op":"user","key":"OLD#1","user_id":1,"at":"` + old + `"}
+{"op":"listing","key":"NEW#2","symbol":"NEW","notice_id":2,"at":"` + recent + `"}
+{"op":"user","key":"NEW#2","user_id":1,"at":"` + recent + `"}
+{"op":"negative","key":"OLD#3","user_id":1,"at":"` + old + `"}
+`
+	if err := os.WriteFile(file, []byte(ledger), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ld := newTestListingDispatcher(t, file)
+	if ld.Handled(ListingInfo{Symbol: "OLD", NoticeID: 1}, 1) {
+		t.Error("expired listing still blocks the user")
+	}
+	if !ld.Handled(ListingInfo{Symbol: "NEW", NoticeID: 2}, 1) {
+		t.Error("recent listing was dropped")
+	}
+	if !ld.ClaimNegative("OLD", 3, 1) {
+		t.Error("expired negative claim still blocks the user")
+	}
+
+	data, _ := os.ReadFile(file)
+	if strings.Contains(string(data), `"OLD#1"`) {
+		t.Errorf("compacted ledger still holds expired records:\n%s", data)
+	}
+}
+
+func TestLedgerLoadFailures(t *testing.T) {
+	valid := `{"op":"listing","key":"FOO#1","symbol":"FOO","notice_id":1,"at":"` + time.Now().Format(time.RFC3339) + `"}` + "\n"
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{"torn last line is dropped", valid + `{"op":"user","key":"FO`, false},
+		{"corrupt line refuses to start", `garbage` + "\n" + valid, true},
+		{"record without op refuses to start", `{"key":"FOO#1"}` + "\n" + valid, true},
+		{"legacy array is converted", `[{"symbol":"FOO","notice_id":1,"sources":{"upbit":"` + time.Now().Format(time.RFC3339) + `"},"users":{"1":"` + time.Now().Format(time.RFC3339) + `"}}]`, false},
+		{"corrupt legacy array refuses to start", `[{"symbol":`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "ledger.json")
+			if err := os.WriteFile(file, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			ld, err := NewListingDispatcher(file)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewListingDispatcher error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil {
+				if ok, _ := ld.Begin(ListingInfo{Symbol: "FOO", NoticeID: 1, Source: ListingSourceUpbit}); ok && tt.name == "legacy array is converted" {
+					t.Error("converted legacy listing dispatched again")
+				}
+			}
+		})
+	}
+
+	unreadable := t.TempDir() // A directory cannot be read as a file
+	if _, err := NewListingDispatcher(unreadable); err == nil {
+		t.Error("unreadable ledger accepted")
+	}
+}
+
+func TestLedgerKeepsCanaryInMemory(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "ledger.json")
+	ld := newTestListingDispatcher(t, file)
+	user := []*UserData{{UserID: 1}}
+
+	for run := 1; run <= 3; run++ {
+		canary := ListingInfo{Symbol: canarySymbol, Source: ListingSourceUpbit, NoticeID: -run, Canary: true}
+		if ok, alert := ld.Begin(canary); !ok || !alert {
+			t.Fatalf("run %d: Begin = %v, %v; want true, true", run, ok, alert)
+		}
+		if len(ld.ClaimUsers(canary, user)) != 1 {
+			t.Fatalf("run %d: canary user claim refused", run)
+		}
+	}
+	if len(ld.entries) != 1 {
+		t.Errorf("ledger holds %d canary entries, want only the latest run", len(ld.entries))
+	}
+	if data, _ := os.ReadFile(file); strings.Contains(string(data), canarySymbol) {
+		t.Errorf("canary written to the ledger file:\n%s", data)
+	}
+}
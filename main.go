@@ -2,12 +2,20 @@ package main
 
 import (
         "log"
-        "time"
+        "os"
+        "os/signal"
+        "syscall"
 
         "github.com/joho/godotenv"
 )
 
 func main() {
+        // Fingerprint check against a local TLS server: go run . tlsprobe
+        if len(os.Args) > 1 && os.Args[1] == "tlsprobe" {
+                runTLSProbe()
+                return
+        }
+
         log.Println("🚀 Upbit-Bitget Auto Trading System Starting...")
 
         _ = godotenv.Load()
@@ -16,62 +24,53 @@ func main() {
         telegramBot := InitializeTelegramBot()
         
         // Create Upbit monitor with DIRECT callback to trading
-        upbitMonitor := NewUpbitMonitor(func(symbol string) {
-                log.Printf("🔥 INSTANT CALLBACK - New Upbit listing: %s", symbol)
-                // DIRECT execution - no file delay!
-                go telegramBot.ExecuteAutoTradeForAllUsers(symbol)
+        upbitMonitor := NewUpbitMonitor(func(listings []ListingInfo) {
+                for _, listing := range listings {
+                        log.Printf("🔥 INSTANT CALLBACK - New Upbit listing: %s (markets: %v, notice #%d)", listing.Symbol, listing.Markets, listing.NoticeID)
+                }
+                // DIRECT dispatch to alerts and trading - no file delay!
+                go telegramBot.DispatchNotice(listings)
         })
         
         // Link monitor to bot for trade logging
         telegramBot.SetUpbitMonitor(upbitMonitor)
+        // Delisting/caution notices close matching positions (and optionally short)
+        upbitMonitor.SetNegativeEventHandler(telegramBot.HandleNegativeEvents)
 
         log.Println("✅ All systems initialized")
         
-        // TIME SYNCHRONIZATION CHECK
+        // TIME SYNCHRONIZATION: startup check, then periodic tracking used for
+        // Bitget request timestamps and detection times
         log.Println("⏰ Checking time synchronization with exchanges...")
-        
-        // Check Upbit time sync
-        upbitSync, err := upbitMonitor.GetServerTime()
-        if err != nil {
-                log.Printf("⚠️ Upbit time sync failed: %v", err)
-        } else {
-                log.Printf("📡 UPBIT TIME SYNC:")
-                log.Printf("   • Server Time: %s", upbitSync.ServerTime.Format("2006-01-02 15:04:05.000"))
-                log.Printf("   • Local Time:  %s", upbitSync.LocalTime.Format("2006-01-02 15:04:05.000"))
-                log.Printf("   • Clock Offset: %v", upbitSync.ClockOffset)
-                log.Printf("   • Network Latency: %v", upbitSync.NetworkLatency)
-                
-                if upbitSync.ClockOffset.Abs() > 1*time.Second {
-                        log.Printf("⚠️ WARNING: Clock offset > 1s! May cause timing issues!")
-                } else {
-                        log.Printf("   ✅ Clock sync OK (offset < 1s)")
-                }
-        }
-        
-        // Check Bitget time sync (create temporary API instance)
-        testBitget := NewBitgetAPI("test", "test", "test")
-        bitgetSync, err := testBitget.GetServerTime()
-        if err != nil {
-                log.Printf("⚠️ Bitget time sync failed: %v", err)
-        } else {
-                log.Printf("📡 BITGET TIME SYNC:")
-                log.Printf("   • Server Time: %s", bitgetSync.ServerTime.Format("2006-01-02 15:04:05.000"))
-                log.Printf("   • Local Time:  %s", bitgetSync.LocalTime.Format("2006-01-02 15:04:05.000"))
-                log.Printf("   • Clock Offset: %v", bitgetSync.ClockOffset)
-                log.Printf("   • Network Latency: %v", bitgetSync.NetworkLatency)
-                
-                if bitgetSync.ClockOffset.Abs() > 1*time.Second {
-                        log.Printf("⚠️ WARNING: Clock offset > 1s! May cause timing issues!")
-                } else {
-                        log.Printf("   ✅ Clock sync OK (offset < 1s)")
-                }
-        }
+        clockSync = NewTimeSyncService(upbitMonitor, telegramBot.notifyAdmins)
+        clockSync.SyncNow()
+        go clockSync.Run(upbitMonitor.stopCh)
         
         log.Println("📡 Starting Upbit monitor...")
         log.Println("🤖 Starting Telegram bot...")
 
+        // Optional end-to-end check of the notice pipeline with a synthetic listing;
+        // set before polling starts because the listing callback reads it
+        if pipelineCanary = NewCanary(upbitMonitor); pipelineCanary != nil {
+                go pipelineCanary.Run(upbitMonitor.stopCh)
+        }
+
         go upbitMonitor.Start()
 
+        // Alert admins if detection silently stops (no 200/304, proxies quarantined, unparseable responses)
+        detectionWatchdog = NewWatchdog(upbitMonitor, telegramBot.notifyAdmins)
+        go detectionWatchdog.Run(upbitMonitor.stopCh)
+
+        // Stop polling cleanly so in-flight checks finish and proxy scores are saved
+        go func() {
+                sigCh := make(chan os.Signal, 1)
+                signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+                <-sigCh
+                log.Println("🛑 Shutdown signal received, stopping Upbit monitor...")
+                upbitMonitor.Stop()
+                os.Exit(0)
+        }()
+
         // Start bot message loop
         telegramBot.Start()
 }
@@ -0,0 +1,440 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NegativeKind is the kind of bad news an Upbit notice carries for a ticker
+type NegativeKind string
+
+const (
+	NegativeDelisting NegativeKind = "delisting" // 거래지원 종료 / 상장폐지
+	NegativeCaution   NegativeKind = "caution"   // 유의 종목 지정 / 투자유의 촉구
+
+	defaultNegativeEventMaxAge = 30 * time.Minute
+	negativeEventPriority      = maxTradePriority + 1 // Protective closes run ahead of queued entries
+	negativeCloseRetries       = 3                    // Extra attempts for longs that failed to close
+	negativeCloseRetryDelay    = 20 * time.Second
+)
+
+// Per-user reactions (UserData.NegativeAction)
+const (
+	NegativeActionClose = "close" // Close matching long positions
+	NegativeActionShort = "short" // Close, then open a short with ShortMarginUSDT/ShortLeverage
+	NegativeActionOff   = "off"   // Default: react only when the user opts in
+)
+
+// NegativeEvent is one ticker named in a delisting or caution notice
+type NegativeEvent struct {
+	Symbol     string
+	Kind       NegativeKind
+	NoticeID   int
+	Title      string
+	Proxy      string
+	ListedAt   time.Time
+	DetectedAt time.Time
+}
+
+// label renders the event kind for user messages
+func (k NegativeKind) label() string {
+	if k == NegativeDelisting {
+		return "işlem desteği sona eriyor (상장폐지)"
+	}
+	return "yatırım uyarısı (유의 종목)"
+}
+
+// koreanMarketRegex matches market names written in Korean ("원화 마켓" = KRW market)
+var (
+	koreanMarketRegex = regexp.MustCompile(`(원화|비트코인|테더)\s*마켓`)
+	koreanMarketCodes = map[string]string{"원화": "KRW", "비트코인": "BTC", "테더": "USDT"}
+)
+
+// noticeMarkets returns the markets a title names (KRW, BTC, USDT), in either script
+func noticeMarkets(title string) map[string]bool {
+	markets := make(map[string]bool)
+	for _, market := range extractMarkets(title) {
+		markets[market] = true
+	}
+	for _, match := range koreanMarketRegex.FindAllStringSubmatch(title, -1) {
+		markets[koreanMarketCodes[match[1]]] = true
+	}
+	return markets
+}
+
+// classifyNegativeNotice maps a negative-filtered title to an event kind; "" for notices that
+// aren't bad news for holders (e.g. a caution designation being lifted, or trading support
+// ending only on the BTC/USDT market while KRW trading continues)
+func classifyNegativeNotice(title string) NegativeKind {
+	switch {
+	case containsAll(title, []string{"거래지원", "종료"}), containsAll(title, []string{"상장폐지"}):
+		if markets := noticeMarkets(title); len(markets) > 0 && !markets["KRW"] {
+			return ""
+		}
+		return NegativeDelisting
+	case containsAll(title, []string{"유의", "해제"}):
+		return ""
+	case containsAll(title, []string{"유의", "종목", "지정"}), containsAll(title, []string{"유의", "촉구"}):
+		return NegativeCaution
+	}
+	return ""
+}
+
+// negativeEventsFor extracts negative events from one notice (nil if it isn't one)
+func (um *UpbitMonitor) negativeEventsFor(announcement Announcement, proxyID string, detectedAt time.Time) []NegativeEvent {
+	kind := classifyNegativeNotice(announcement.Title)
+	if kind == "" {
+		return nil
+	}
+	listedAt, _ := time.Parse(time.RFC3339, announcement.ListedAt)
+
+	var events []NegativeEvent
+	for _, ticker := range extractTickers(announcement.Title) {
+		events = append(events, NegativeEvent{
+			Symbol:     ticker,
+			Kind:       kind,
+			NoticeID:   announcement.ID,
+			Title:      announcement.Title,
+			Proxy:      um.proxyDisplayName(proxyID),
+			ListedAt:   listedAt,
+			DetectedAt: detectedAt,
+		})
+	}
+	return events
+}
+
+// SetNegativeEventHandler registers the callback for delisting/caution notices
+func (um *UpbitMonitor) SetNegativeEventHandler(handler func(events []NegativeEvent)) {
+	um.mu.Lock()
+	um.onNegativeEvent = handler
+	um.mu.Unlock()
+}
+
+// dispatchNegativeEventsLocked hands fresh, unseen events to the handler. Notices older than
+// NEGATIVE_EVENT_MAX_AGE_MIN are only marked seen, so a restart doesn't replay old delistings.
+func (um *UpbitMonitor) dispatchNegativeEventsLocked(events []NegativeEvent) {
+	if len(events) == 0 {
+		return
+	}
+	if um.negativeSeen == nil {
+		um.negativeSeen = make(map[string]bool)
+	}
+	maxAge := envDuration("NEGATIVE_EVENT_MAX_AGE_MIN", time.Minute, defaultNegativeEventMaxAge)
+
+	var fresh []NegativeEvent
+	for _, event := range events {
+		key := fmt.Sprintf("%d:%s", event.NoticeID, event.Symbol)
+		if um.negativeSeen[key] {
+			continue
+		}
+		um.negativeSeen[key] = true
+		if event.ListedAt.IsZero() || event.DetectedAt.Sub(event.ListedAt) > maxAge {
+			continue
+		}
+		log.Printf("🚨 Negative notice #%d: %s %s (%s)", event.NoticeID, event.Symbol, event.Kind, event.Title)
+		fresh = append(fresh, event)
+	}
+	if len(fresh) > 0 && um.onNegativeEvent != nil {
+		go um.onNegativeEvent(fresh)
+	}
+}
+
+// negativeAction returns the user's reaction, defaulting to off
+func (u *UserData) negativeAction() string {
+	if u.NegativeAction == "" {
+		return NegativeActionOff
+	}
+	return u.NegativeAction
+}
+
+// formatNegativeAction renders the reaction for the filters screen
+func (u *UserData) formatNegativeAction() string {
+	switch u.negativeAction() {
+	case NegativeActionOff:
+		return "kapalı"
+	case NegativeActionShort:
+		margin, leverage := u.shortSizing()
+		return fmt.Sprintf("long kapat + short aç (%.2f USDT x%d)", margin, leverage)
+	default:
+		return "long pozisyonları kapat"
+	}
+}
+
+// shortSizing returns the short margin and leverage, falling back to the long settings
+func (u *UserData) shortSizing() (float64, int) {
+	margin, leverage := u.ShortMarginUSDT, u.ShortLeverage
+	if margin <= 0 {
+		margin = u.MarginUSDT
+	}
+	if leverage <= 0 {
+		leverage = u.Leverage
+	}
+	return margin, leverage
+}
+
+// parseNegativeArgs parses "/negative off|close|short [margin] [leverage]"
+func parseNegativeArgs(args string) (action string, margin float64, leverage int, err error) {
+	fields := strings.Fields(strings.ToLower(args))
+	if len(fields) == 0 {
+		return "", 0, 0, fmt.Errorf("tepki eksik")
+	}
+	switch fields[0] {
+	case NegativeActionOff, NegativeActionClose:
+		if len(fields) > 1 {
+			return "", 0, 0, fmt.Errorf("%s parametre almaz", fields[0])
+		}
+		return fields[0], 0, 0, nil
+	case NegativeActionShort:
+	default:
+		return "", 0, 0, fmt.Errorf("bilinmeyen tepki %q", fields[0])
+	}
+
+	if len(fields) > 3 {
+		return "", 0, 0, fmt.Errorf("fazla parametre")
+	}
+	if len(fields) > 1 {
+		if margin, err = strconv.ParseFloat(fields[1], 64); err != nil || margin <= 0 {
+			return "", 0, 0, fmt.Errorf("geçersiz marjin")
+		}
+	}
+	if len(fields) > 2 {
+		if leverage, err = strconv.Atoi(strings.TrimSuffix(fields[2], "x")); err != nil || leverage < 1 || leverage > 125 {
+			return "", 0, 0, fmt.Errorf("kaldıraç 1-125 arasında olmalı")
+		}
+	}
+	return NegativeActionShort, margin, leverage, nil
+}
+
+// HandleNegativeEvents queues, for every user who opted in, closing their tracked longs on the
+// named tickers and, for users who chose it, opening a short; the jobs run on the trade executor
+func (tb *TelegramBot) HandleNegativeEvents(events []NegativeEvent) {
+	users := tb.getAllActiveUsers()
+	for _, event := range events {
+		symbols := negativeEventSymbols(event)
+		log.Printf("🚨 Reacting to %s of %s for %d users (contracts %v)", event.Kind, event.Symbol, len(users), symbols)
+		for _, user := range users {
+			if user.negativeAction() == NegativeActionOff || user.BitgetAPIKey == "" {
+				continue
+			}
+			tb.executor.SubmitTask(user, negativeEventPriority, func() {
+				tb.reactToNegativeEvent(user, event, symbols, 0)
+			})
+		}
+	}
+}
+
+// negativeEventSymbols returns the Bitget contracts a ticker may be held under
+func negativeEventSymbols(event NegativeEvent) map[string]bool {
+	symbols := map[string]bool{event.Symbol + "USDT": true}
+	for _, multiplier := range contractMultipliers {
+		symbols[multiplier+event.Symbol+"USDT"] = true
+	}
+	if mapping, err := symbolMapper.Resolve(ListingInfo{Symbol: event.Symbol, Title: event.Title}); err == nil {
+		symbols[mapping.Symbol] = true
+	}
+	return symbols
+}
+
+// reactToNegativeEvent closes the user's matching longs and, once none is left open, opens the
+// short, so a hedge-mode account never holds both sides. The reaction is recorded once every long
+// closed; longs that failed to close are retried on the executor up to negativeCloseRetries
+// times, and the attempt that finally closes them opens the short.
+func (tb *TelegramBot) reactToNegativeEvent(user *UserData, event NegativeEvent, symbols map[string]bool, attempt int) {
+	if attempt == 0 && tb.dispatcher.NegativeHandled(event.Symbol, event.NoticeID, user.UserID) {
+		log.Printf("🔄 User %d already reacted to notice #%d for %s, skipping", user.UserID, event.NoticeID, event.Symbol)
+		return // E.g. the notice was replayed after a restart
+	}
+
+	var held []*PositionInfo
+	positionsMutex.RLock()
+	for _, position := range activePositions {
+		if position.UserID == user.UserID && symbols[position.Symbol] {
+			held = append(held, position)
+		}
+	}
+	positionsMutex.RUnlock()
+
+	short := user.negativeAction() == NegativeActionShort && !user.IsPaused
+	if len(held) == 0 && !short {
+		if attempt > 0 {
+			tb.recordNegative(user, event) // Closed elsewhere since the failed attempt
+		}
+		return
+	}
+
+	api := bitgetClientFor(user)
+	var b strings.Builder
+	fmt.Fprintf(&b, "🚨 UPBIT UYARISI: %s - %s", event.Symbol, event.Kind.label())
+
+	failed := 0
+	for _, position := range held {
+		if _, err := api.FlashClosePosition(position.Symbol, "long"); err != nil {
+			log.Printf("❌ User %d: closing %s on %s failed (attempt %d): %v", user.UserID, position.Symbol, event.Kind, attempt+1, err)
+			fmt.Fprintf(&b, "\n❌ %s long kapatılamadı: %v", position.Symbol, err)
+			failed++
+			continue
+		}
+
+		positionKey := fmt.Sprintf("%d_%s", user.UserID, position.Symbol)
+		positionsMutex.Lock()
+		delete(activePositions, positionKey)
+		positionsMutex.Unlock()
+		go saveActivePositions()
+
+		eventBus.Publish(EventPositionClosed, user.UserID, PositionClosedEvent{
+			Symbol:  position.Symbol,
+			OrderID: position.OrderID,
+			Reason:  string(event.Kind),
+		})
+		log.Printf("🛑 User %d: closed %s long on %s notice #%d", user.UserID, position.Symbol, event.Kind, event.NoticeID)
+		fmt.Fprintf(&b, "\n✅ %s long pozisyonu kapatıldı", position.Symbol)
+	}
+
+	if failed == 0 {
+		if short {
+			tb.openNegativeShort(user, api, event, &b)
+		}
+		tb.recordNegative(user, event) // Also keeps a replayed notice from opening the short twice
+	} else {
+		if short {
+			b.WriteString("\n⏸️ Short, long pozisyonları kapanana kadar açılmayacak")
+		}
+		if attempt < negativeCloseRetries {
+			fmt.Fprintf(&b, "\n🔁 Kapatılamayan pozisyonlar %s sonra tekrar denenecek", negativeCloseRetryDelay)
+			time.AfterFunc(negativeCloseRetryDelay, func() {
+				tb.executor.SubmitTask(user, negativeEventPriority, func() {
+					tb.reactToNegativeEvent(user, event, symbols, attempt+1)
+				})
+			})
+		} else {
+			log.Printf("❌ User %d: giving up closing %d %s longs after %d attempts", user.UserID, failed, event.Symbol, attempt+1)
+			b.WriteString("\n⚠️ Lütfen pozisyonları Bitget'ten elle kapatın")
+		}
+	}
+
+	b.WriteString(formatNoticeDetails(ListingInfo{NoticeID: event.NoticeID, Title: event.Title}))
+	tb.sendMessage(user.UserID, b.String())
+}
+
+// recordNegative persists a finished reaction unless an earlier attempt already did
+func (tb *TelegramBot) recordNegative(user *UserData, event NegativeEvent) {
+	if !tb.dispatcher.NegativeHandled(event.Symbol, event.NoticeID, user.UserID) {
+		tb.dispatcher.ClaimNegative(event.Symbol, event.NoticeID, user.UserID)
+	}
+}
+
+// openNegativeShort opens the user's short on the event's contract and appends the result to b
+func (tb *TelegramBot) openNegativeShort(user *UserData, api *BitgetAPI, event NegativeEvent, b *strings.Builder) {
+	mapping, err := symbolMapper.Resolve(ListingInfo{Symbol: event.Symbol, Title: event.Title})
+	if err != nil {
+		fmt.Fprintf(b, "\n⏭️ Short açılmadı: %v", err)
+		return
+	}
+
+	margin, leverage := user.shortSizing()
+	result, err := api.OpenShortPosition(mapping.Symbol, margin, leverage)
+	if err != nil {
+		log.Printf("❌ User %d: short %s failed: %v", user.UserID, mapping.Symbol, err)
+		fmt.Fprintf(b, "\n❌ %s short açılamadı: %v", mapping.Symbol, err)
+		eventBus.Publish(EventOrderFailed, user.UserID, OrderFailedEvent{
+			Symbol:     mapping.Symbol,
+			MarginUSDT: margin,
+			Leverage:   leverage,
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	eventBus.Publish(EventOrderPlaced, user.UserID, OrderPlacedEvent{
+		Symbol:     result.Symbol,
+		OrderID:    result.OrderID,
+		Side:       string(OrderSideSell),
+		Size:       result.Size,
+		Price:      result.OpenPrice,
+		MarginUSDT: result.MarginUSDT,
+		Leverage:   result.Leverage,
+	})
+	log.Printf("📉 User %d: short %s %.8g @ %.8g (%.2f USDT x%d)", user.UserID, result.Symbol, result.Size, result.OpenPrice, margin, leverage)
+	if !result.FillConfirmed && result.OrderID != "" {
+		go tb.confirmShortFill(api, user.UserID, result.Symbol, result.OrderID)
+	}
+	fmt.Fprintf(b, "\n📉 %s short açıldı: %.8f @ $%.4f (%.2f USDT x%d)\nShort pozisyonu hatırlatıcılarla takip edilmez; Bitget'ten veya 📈 Tüm Pozisyonlar ile kontrol edin.",
+		result.Symbol, result.Size, result.OpenPrice, margin, leverage)
+}
+
+// confirmShortFill re-reads a short's fill after placement. Shorts aren't tracked, so the fill is
+// only logged, and the user is told if the order ended without filling.
+func (tb *TelegramBot) confirmShortFill(api *BitgetAPI, userID int64, symbol, orderID string) {
+	var err error
+	for i := 0; i < fillConfirmAttempts; i++ {
+		time.Sleep(fillConfirmDelay)
+		var detail *OrderDetail
+		detail, err = readFill(api, symbol, orderID)
+		if errors.Is(err, errNoFill) {
+			log.Printf("🚫 User %d: short %s order %s %s with nothing filled", userID, symbol, orderID, detail.State)
+			tb.sendMessage(userID, fmt.Sprintf("🚫 %s short emri hiç dolmadan kapandı (%s); short açılmadı.", symbol, detail.State))
+			return
+		}
+		if err == nil {
+			log.Printf("🧾 User %d: short %s fill confirmed: %.8g @ %.8g, fee %.4f USDT",
+				userID, symbol, detail.Filled(), detail.AvgPrice(), detail.FeeUSDT())
+			return
+		}
+	}
+	log.Printf("⚠️ User %d: short %s order %s fill still unknown: %v", userID, symbol, orderID, err)
+}
+
+// OpenShortPosition market-sells an opening short sized margin x leverage at the last price
+func (b *BitgetAPI) OpenShortPosition(symbol string, marginUSDT float64, leverage int) (*OrderResponse, error) {
+	sufficient, err := b.Cache.HasSufficientBalance(marginUSDT)
+	if err != nil {
+		return nil, fmt.Errorf("balance check failed: %w", err)
+	}
+	if !sufficient {
+		return nil, fmt.Errorf("insufficient balance: %.2f USDT required", marginUSDT)
+	}
+	if err := b.SetLeverage(symbol, leverage); err != nil {
+		return nil, fmt.Errorf("failed to set leverage: %w", err)
+	}
+	price, err := b.GetSymbolPrice(symbol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current price: %w", err)
+	}
+
+	size := marginUSDT * float64(leverage) / price
+	if _, sizeDecimals, _, err := b.contractPrecision(symbol); err == nil {
+		size = floorTo(size, sizeDecimals)
+	}
+	order, err := b.PlaceOrder(symbol, OrderSideSell, size, "open")
+	if err != nil {
+		return nil, fmt.Errorf("order placement failed: %w", err)
+	}
+
+	order.Symbol = symbol
+	order.OpenPrice = price
+	order.Size = size
+	order.MarginUSDT = marginUSDT
+	order.Leverage = leverage
+	order.QuotePrice = price
+	order.RequestedSize = size
+
+	// Like the long market entry: one read, never waited on or cancelled; the caller confirms
+	// a fill that isn't final yet in the background
+	detail, err := b.GetOrderDetail(symbol, order.OrderID)
+	if err == nil && detail.Final() && detail.Filled() == 0 {
+		return nil, fmt.Errorf("market order %s %s with nothing filled", order.OrderID, detail.State)
+	}
+	if err == nil && detail.Final() && detail.AvgPrice() > 0 {
+		order.OpenPrice = detail.AvgPrice()
+		order.Size = detail.Filled()
+		order.FeeUSDT = detail.FeeUSDT()
+		order.FilledAt = detail.FilledAt()
+		order.FillConfirmed = true
+	}
+	return order, nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import "testing"
+
+func TestClassifyNegativeNotice(t *testing.T) {
+	tests := []struct {
+		title string
+		want  NegativeKind
+	}{
+		{"거래지원 종료 안내 (ABC)", NegativeDelisting},
+		{"[거래지원 종료] 에이비씨(ABC) 거래지원 종료 안내", NegativeDelisting},
+		{"에이비씨(ABC) 상장폐지 안내", NegativeDelisting},
+		{"KRW 마켓 에이비씨(ABC) 거래지원 종료 안내", NegativeDelisting},
+		{"KRW, BTC 마켓 에이비씨(ABC) 거래지원 종료 안내", NegativeDelisting},
+		{"원화 마켓 에이비씨(ABC) 거래지원 종료 안내", NegativeDelisting},
+		{"BTC 마켓 에이비씨(ABC) 거래지원 종료 안내", ""},
+		{"USDT 마켓 에이비씨(ABC) 거래지원 종료 안내", ""},
+		{"BTC/USDT 마켓 에이비씨(ABC) 거래지원 종료 안내", ""},
+		{"비트코인 마켓 에이비씨(ABC) 거래지원 종료 안내", ""},
+		{"테더 마켓 에이비씨(ABC) 거래지원 종료 안내", ""},
+		{"유의 종목 지정 안내 (ABC)", NegativeCaution},
+		{"투자유의 촉구 안내 (ABC)", NegativeCaution},
+		{"유의 종목 지정 해제 안내 (ABC)", ""},
+		{"에이비씨(ABC) KRW 마켓 디지털 자산 추가", ""},
+	}
+	for _, tt := range tests {
+		if got := classifyNegativeNotice(tt.title); got != tt.want {
+			t.Errorf("classifyNegativeNotice(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestNegativeActionDefaultsOff(t *testing.T) {
+	if got := (&UserData{}).negativeAction(); got != NegativeActionOff {
+		t.Errorf("default negative action = %q, want %q", got, NegativeActionOff)
+	}
+}
+
+func TestParseNegativeArgs(t *testing.T) {
+	tests := []struct {
+		args     string
+		action   string
+		margin   float64
+		leverage int
+		wantErr  bool
+	}{
+		{"off", NegativeActionOff, 0, 0, false},
+		{"CLOSE", NegativeActionClose, 0, 0, false},
+		{"short", NegativeActionShort, 0, 0, false},
+		{"short 50 5x", NegativeActionShort, 50, 5, false},
+		{"", "", 0, 0, true},
+		{"close 5", "", 0, 0, true},
+		{"short -1", "", 0, 0, true},
+		{"short 50 200", "", 0, 0, true},
+		{"short 50 5 1", "", 0, 0, true},
+		{"flip", "", 0, 0, true},
+	}
+	for _, tt := range tests {
+		action, margin, leverage, err := parseNegativeArgs(tt.args)
+		if (err != nil) != tt.wantErr || action != tt.action || margin != tt.margin || leverage != tt.leverage {
+			t.Errorf("parseNegativeArgs(%q) = (%q, %v, %d, %v), want (%q, %v, %d, err=%v)",
+				tt.args, action, margin, leverage, err, tt.action, tt.margin, tt.leverage, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"log"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+)
+
+// Polling modes selectable with UPBIT_POLL_MODE
+const (
+	PollModeRandom  = "random"  // One loop, scheduler picks a proxy per request
+	PollModeWorkers = "workers" // One paced goroutine per proxy with staggered phases
+)
+
+// loadPollMode reads UPBIT_POLL_MODE, defaulting to the random loop
+func loadPollMode() string {
+	mode := strings.ToLower(strings.TrimSpace(os.Getenv("UPBIT_POLL_MODE")))
+	switch mode {
+	case "":
+		return PollModeRandom
+	case PollModeRandom, PollModeWorkers:
+		return mode
+	default:
+		log.Printf("⚠️ Unknown UPBIT_POLL_MODE '%s', using %s", mode, PollModeRandom)
+		return PollModeRandom
+	}
+}
+
+// pauseCheckInterval is how long pollers sleep between quiet-hours checks (5-10s)
+func pauseCheckInterval() time.Duration {
+	return time.Duration(5000+rand.Intn(5000)) * time.Millisecond
+}
+
+// sleepOrStop sleeps for d and reports false if the monitor (or the optional extra channel) stopped first
+func (um *UpbitMonitor) sleepOrStop(d time.Duration, extra <-chan struct{}) bool {
+	if d <= 0 {
+		select {
+		case <-um.stopCh:
+			return false
+		case <-extra:
+			return false
+		default:
+			return true
+		}
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-um.stopCh:
+		return false
+	case <-extra:
+		return false
+	}
+}
+
+// Stop ends polling in either mode, waits for in-flight checks and saves proxy scores
+func (um *UpbitMonitor) Stop() {
+	um.stopOnce.Do(func() { close(um.stopCh) })
+	um.running.Wait()
+}
+
+// runWorkers runs one worker per proxy and restarts them with fresh phases when the proxy list changes
+func (um *UpbitMonitor) runWorkers() {
+	for {
+		proxies := um.Proxies()
+		generation := make(chan struct{})
+		epoch := time.Now()
+
+		for slot, config := range proxies {
+			um.running.Add(1)
+			go func(proxyID string, slot int) {
+				defer um.running.Done()
+				um.proxyWorker(proxyID, slot, len(proxies), epoch, generation)
+			}(config.ID(), slot)
+		}
+		log.Printf("👷 %d proxy workers started, %v apart", len(proxies),
+			(um.rateController.WorkerInterval(um.healthyProxyCount()) / time.Duration(max(len(proxies), 1))).Round(time.Millisecond))
+
+		select {
+		case <-um.proxiesChanged:
+			// Old workers finish their in-flight request and exit; new ones take over the slots
+			close(generation)
+			log.Printf("👷 Proxy list changed, re-staggering workers")
+		case <-um.stopCh:
+			close(generation)
+			return
+		}
+	}
+}
+
+// proxyWorker polls one proxy on its own grid: slot/n of a period after epoch, then every period.
+// A slow or failing proxy only delays its own slot; quarantined proxies sit out their ticks.
+func (um *UpbitMonitor) proxyWorker(proxyID string, slot, n int, epoch time.Time, done <-chan struct{}) {
+	period := um.rateController.WorkerInterval(um.healthyProxyCount())
+	next := epoch.Add(period * time.Duration(slot) / time.Duration(n))
+
+	for {
+		// ±20% of the slot spacing keeps workers from a perfectly regular cadence without overlapping
+		spacing := float64(period) / float64(n)
+		jitter := time.Duration(spacing * rateJitter * (2*rand.Float64() - 1))
+		if !um.sleepOrStop(time.Until(next)+jitter, done) {
+			return
+		}
+
+		period = um.rateController.WorkerInterval(um.healthyProxyCount())
+		next = next.Add(period)
+
+		if um.pausedNow() {
+			if !um.sleepOrStop(pauseCheckInterval(), done) {
+				return
+			}
+			next = alignToGrid(next, period)
+			continue
+		}
+
+		um.cooldownMu.RLock()
+		cooldownUntil := um.proxyCooldowns[proxyID]
+		um.cooldownMu.RUnlock()
+		if time.Now().Before(cooldownUntil) {
+			continue
+		}
+
+		outcome := um.checkProxy(proxyID)
+		um.reportOutcome(proxyID, outcome)
+		um.rateController.Report(outcome)
+
+		// A request that overran its period skips the missed ticks instead of bursting
+		next = alignToGrid(next, period)
+	}
+}
+
+// alignToGrid moves next forward by whole periods until it is in the future, keeping the worker's phase
+func alignToGrid(next time.Time, period time.Duration) time.Time {
+	if period <= 0 {
+		return time.Now()
+	}
+	if behind := time.Since(next); behind > 0 {
+		next = next.Add((behind/period + 1) * period)
+	}
+	return next
+}
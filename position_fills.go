@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// A market entry whose fill isn't final right after placement is re-read this often, off the worker
+const (
+	fillConfirmAttempts = 5
+	fillConfirmDelay    = 2 * time.Second
+)
+
+// positionPnL returns the price change, change % and USDT P&L of a long entered at the average
+// fill openPrice for size coins, net of entry fees
+func positionPnL(openPrice, size, feeUSDT, currentPrice float64) (change, changePct, pnl float64) {
+	if openPrice <= 0 {
+		return 0, 0, 0
+	}
+	change = currentPrice - openPrice
+	changePct = change / openPrice * 100
+	pnl = change*size - feeUSDT
+	return change, changePct, pnl
+}
+
+// errNoFill means a market order reached a final state without filling anything
+var errNoFill = errors.New("order ended with nothing filled")
+
+// readFill returns an order's final fill; errNoFill when it ended unfilled, another error while
+// it is unreadable or still live
+func readFill(api *BitgetAPI, symbol, orderID string) (*OrderDetail, error) {
+	detail, err := api.GetOrderDetail(symbol, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if !detail.Final() {
+		return nil, fmt.Errorf("order %s", detail.State)
+	}
+	if detail.Filled() <= 0 {
+		return detail, errNoFill
+	}
+	if detail.AvgPrice() <= 0 {
+		return nil, fmt.Errorf("order %s without an average price", detail.State)
+	}
+	return detail, nil
+}
+
+// confirmFill replaces a position's quoted entry with Bitget's order detail when the fill
+// couldn't be read right after placement. An order that ended unfilled never opened a position,
+// so it stops being tracked and the user is told; false means the position is gone.
+func (tb *TelegramBot) confirmFill(api *BitgetAPI, position *PositionInfo) bool {
+	positionsMutex.RLock()
+	pending := !position.FillConfirmed && position.OrderID != ""
+	userID, symbol, orderID := position.UserID, position.Symbol, position.OrderID
+	positionsMutex.RUnlock()
+	if !pending {
+		return true
+	}
+
+	detail, err := readFill(api, symbol, orderID)
+	if errors.Is(err, errNoFill) {
+		log.Printf("🚫 %s order %s %s with nothing filled, untracking user %d's position", symbol, orderID, detail.State, userID)
+		positionKey := fmt.Sprintf("%d_%s", userID, symbol)
+		positionsMutex.Lock()
+		if activePositions[positionKey] == position {
+			delete(activePositions, positionKey)
+		}
+		positionsMutex.Unlock()
+		go saveActivePositions()
+
+		tb.sendMessage(userID, fmt.Sprintf("🚫 %s emri hiç dolmadan kapandı (%s); pozisyon açılmadı ve takipten çıkarıldı.", symbol, detail.State))
+		return false
+	}
+	if err != nil {
+		log.Printf("⚠️ Fill for %s order %s still unknown: %v", symbol, orderID, err)
+		return true
+	}
+
+	positionsMutex.Lock()
+	log.Printf("🧾 %s fill confirmed: %.8g @ %.8g (was %.8g @ %.8g), fee %.4f USDT",
+		symbol, detail.Filled(), detail.AvgPrice(), position.Size, position.OpenPrice, detail.FeeUSDT())
+	position.OpenPrice = detail.AvgPrice()
+	position.Size = detail.Filled()
+	position.FeeUSDT = detail.FeeUSDT()
+	position.FilledAt = detail.FilledAt()
+	position.FillConfirmed = true
+	positionsMutex.Unlock()
+
+	go saveActivePositions()
+	return true
+}
+
+// confirmFillInBackground re-reads a market entry's fill a few times after placement, so the
+// position shows the real price well before the first reminder retries it
+func (tb *TelegramBot) confirmFillInBackground(api *BitgetAPI, position *PositionInfo) {
+	for i := 0; i < fillConfirmAttempts; i++ {
+		time.Sleep(fillConfirmDelay)
+		if !tb.confirmFill(api, position) {
+			return
+		}
+
+		positionsMutex.RLock()
+		done := position.FillConfirmed
+		positionsMutex.RUnlock()
+		if done {
+			return
+		}
+	}
+}
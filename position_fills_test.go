@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestPositionPnL(t *testing.T) {
+	tests := []struct {
+		name                      string
+		openPrice, size, fee, now float64
+		change, changePct, pnl    float64
+	}{
+		{"gain net of fee", 2, 100, 0.5, 2.5, 0.5, 25, 49.5},
+		{"loss adds the fee", 2, 100, 0.5, 1.5, -0.5, -25, -50.5},
+		{"flat pays only the fee", 1, 10, 0.1, 1, 0, 0, -0.1},
+		{"no fee", 0.0004, 1e6, 0, 0.0005, 0.0001, 25, 100},
+		{"unknown entry is zero", 0, 100, 0.5, 2, 0, 0, 0},
+		{"negative entry is zero", -1, 100, 0.5, 2, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		change, changePct, pnl := positionPnL(tt.openPrice, tt.size, tt.fee, tt.now)
+		if !closeTo(change, tt.change) || !closeTo(changePct, tt.changePct) || !closeTo(pnl, tt.pnl) {
+			t.Errorf("%s: positionPnL = (%v, %v, %v), want (%v, %v, %v)",
+				tt.name, change, changePct, pnl, tt.change, tt.changePct, tt.pnl)
+		}
+	}
+}
+
+func closeTo(got, want float64) bool {
+	return math.Abs(got-want) < 1e-9
+}
+
+func TestReadFill(t *testing.T) {
+	tests := []struct {
+		name       string
+		server     *fakeOrderServer
+		wantFilled float64
+		wantNoFill bool
+		wantErr    bool
+	}{
+		{"filled order", &fakeOrderServer{state: "filled", filled: "10"}, 10, false, false},
+		{"partial fill then canceled", &fakeOrderServer{state: "canceled", filled: "4"}, 4, false, false},
+		{"canceled with nothing filled", &fakeOrderServer{state: "canceled", filled: "0"}, 0, true, true},
+		{"still live", &fakeOrderServer{state: "live", filled: "0"}, 0, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			detail, err := readFill(newFakeBitget(t, tt.server), "FOOUSDT", "1")
+			if (err != nil) != tt.wantErr || errors.Is(err, errNoFill) != tt.wantNoFill {
+				t.Fatalf("error = %v, wantErr %v, wantNoFill %v", err, tt.wantErr, tt.wantNoFill)
+			}
+			if err == nil && detail.Filled() != tt.wantFilled {
+				t.Errorf("filled = %v, want %v", detail.Filled(), tt.wantFilled)
+			}
+		})
+	}
+}
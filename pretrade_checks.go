@@ -0,0 +1,211 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxSpreadPct   = 1.0    // Best ask vs best bid, % of mid
+	defaultDepthBandPct   = 2.0    // Ask depth is summed up to this % above the best ask
+	defaultMinDepthUSDT   = 2000.0 // Less ask depth than this in the band skips the trade
+	defaultMaxDepthShare  = 0.5    // Position notional above this share of the band depth is downsized
+	defaultMaxPreMovePct  = 30.0   // Price change since detection above this skips the trade
+	minOrderNotionalUSDT  = 5.0    // Bitget's smallest USDT-M order
+	marketSnapshotMaxAge  = time.Second
+	marketSnapshotTimeout = 2 * time.Second
+	orderBookLevels       = 100
+)
+
+// PreTradeLimits are the market sanity thresholds checked before each entry
+type PreTradeLimits struct {
+	Enabled       bool
+	FailOpen      bool // Trade unchecked when market data is unavailable (default: skip)
+	MaxSpreadPct  float64
+	DepthBandPct  float64
+	MinDepthUSDT  float64
+	MaxDepthShare float64
+	MaxPreMovePct float64
+}
+
+// loadPreTradeLimits reads PRETRADE_* (PRETRADE_CHECKS=false disables all checks,
+// PRETRADE_ON_ERROR=trade trades unchecked when the book can't be read)
+func loadPreTradeLimits() PreTradeLimits {
+	return PreTradeLimits{
+		Enabled:       os.Getenv("PRETRADE_CHECKS") != "false",
+		FailOpen:      os.Getenv("PRETRADE_ON_ERROR") == "trade",
+		MaxSpreadPct:  envFloat("PRETRADE_MAX_SPREAD_PCT", defaultMaxSpreadPct),
+		DepthBandPct:  envFloat("PRETRADE_DEPTH_BAND_PCT", defaultDepthBandPct),
+		MinDepthUSDT:  envFloat("PRETRADE_MIN_DEPTH_USDT", defaultMinDepthUSDT),
+		MaxDepthShare: envFloat("PRETRADE_MAX_DEPTH_SHARE", defaultMaxDepthShare),
+		MaxPreMovePct: envFloat("PRETRADE_MAX_PREMOVE_PCT", defaultMaxPreMovePct),
+	}
+}
+
+var preTradeLimits = loadPreTradeLimits()
+
+// MarketSnapshot is what the checks look at for one contract
+type MarketSnapshot struct {
+	Symbol     string
+	BestBid    float64
+	BestAsk    float64
+	SpreadPct  float64
+	AskDepth   float64 // USDT on the ask side within DepthBandPct of the best ask
+	RefPrice   float64 // Open of the 1m bar the listing was detected in (0 if unknown)
+	PreMovePct float64 // Best ask vs RefPrice
+	FetchedAt  time.Time
+}
+
+// PreTradeVerdict is the outcome of the checks for one user's entry
+type PreTradeVerdict struct {
+	Skip       bool
+	MarginUSDT float64 // Margin to trade with (downsized when the book is thin)
+	Reasons    []string
+}
+
+var (
+	marketSnapshotsMu sync.Mutex
+	marketSnapshots   = make(map[string]*MarketSnapshot)
+)
+
+// fetchMarketSnapshot reads the book and 1m bars for a contract; users trading the same listing
+// within marketSnapshotMaxAge share one snapshot
+func fetchMarketSnapshot(symbol string, detectedAt time.Time, bandPct float64) (*MarketSnapshot, error) {
+	marketSnapshotsMu.Lock()
+	if snapshot, ok := marketSnapshots[symbol]; ok && time.Since(snapshot.FetchedAt) < marketSnapshotMaxAge {
+		marketSnapshotsMu.Unlock()
+		return snapshot, nil
+	}
+	marketSnapshotsMu.Unlock()
+
+	api := bitgetPublicClient(marketSnapshotTimeout)
+
+	var (
+		wg        sync.WaitGroup
+		book      *OrderBook
+		bookErr   error
+		candles   []Candle
+		candleErr error
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		book, bookErr = api.GetOrderBook(symbol, orderBookLevels)
+	}()
+	if !detectedAt.IsZero() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			candles, candleErr = api.GetCandles(symbol, "1m", detectedAt.Truncate(time.Minute), 10)
+		}()
+	}
+	wg.Wait()
+
+	if errors.Is(bookErr, errEmptyOrderBook) {
+		// No levels is a real answer: zero depth, so the checks skip the trade
+		return &MarketSnapshot{Symbol: symbol, FetchedAt: time.Now()}, nil
+	}
+	if bookErr != nil {
+		return nil, fmt.Errorf("order book: %w", bookErr)
+	}
+	if candleErr != nil {
+		log.Printf("⚠️ No 1m bars for %s, skipping pre-move check: %v", symbol, candleErr)
+	}
+
+	bid, ask := book.Bids[0][0], book.Asks[0][0]
+	snapshot := &MarketSnapshot{
+		Symbol:    symbol,
+		BestBid:   bid,
+		BestAsk:   ask,
+		SpreadPct: (ask - bid) / ((ask + bid) / 2) * 100,
+		FetchedAt: time.Now(),
+	}
+	limit := ask * (1 + bandPct/100)
+	for _, level := range book.Asks {
+		if level[0] > limit {
+			break
+		}
+		snapshot.AskDepth += level[0] * level[1]
+	}
+	if len(candles) > 0 && candles[0].Open > 0 {
+		snapshot.RefPrice = candles[0].Open
+		snapshot.PreMovePct = (ask/snapshot.RefPrice - 1) * 100
+	}
+
+	marketSnapshotsMu.Lock()
+	marketSnapshots[symbol] = snapshot
+	marketSnapshotsMu.Unlock()
+	return snapshot, nil
+}
+
+// Check applies the limits to a planned entry of marginUSDT at the given leverage
+func (l PreTradeLimits) Check(snapshot *MarketSnapshot, marginUSDT float64, leverage int) PreTradeVerdict {
+	verdict := PreTradeVerdict{MarginUSDT: marginUSDT}
+	skip := func(format string, args ...interface{}) {
+		verdict.Skip = true
+		verdict.Reasons = append(verdict.Reasons, fmt.Sprintf(format, args...))
+	}
+
+	if snapshot.BestBid <= 0 || snapshot.BestAsk <= 0 {
+		skip("emir defteri boş")
+		return verdict
+	}
+	if snapshot.SpreadPct > l.MaxSpreadPct {
+		skip("spread %%%.2f > %%%.2f", snapshot.SpreadPct, l.MaxSpreadPct)
+	}
+	if snapshot.AskDepth < l.MinDepthUSDT {
+		skip("%%%.1f içindeki derinlik %.0f USDT < %.0f USDT", l.DepthBandPct, snapshot.AskDepth, l.MinDepthUSDT)
+	}
+	if snapshot.RefPrice > 0 && snapshot.PreMovePct > l.MaxPreMovePct {
+		skip("tespitten beri fiyat %%%.1f yükselmiş > %%%.1f", snapshot.PreMovePct, l.MaxPreMovePct)
+	}
+	if verdict.Skip || leverage <= 0 {
+		return verdict
+	}
+
+	maxNotional := snapshot.AskDepth * l.MaxDepthShare
+	if notional := marginUSDT * float64(leverage); notional > maxNotional {
+		verdict.MarginUSDT = maxNotional / float64(leverage)
+		if maxNotional < minOrderNotionalUSDT {
+			skip("derinlik çok sığ (%.0f USDT)", snapshot.AskDepth)
+			return verdict
+		}
+		verdict.Reasons = append(verdict.Reasons, fmt.Sprintf("derinlik %.0f USDT, marjin %.2f → %.2f USDT küçültüldü",
+			snapshot.AskDepth, marginUSDT, verdict.MarginUSDT))
+	}
+	return verdict
+}
+
+// preTradeCheck runs the checks for one job; without market data the trade is skipped,
+// or traded unchecked when FailOpen is set, and either way the user is told why
+func preTradeCheck(job *TradeJob, symbol string, marginUSDT float64) PreTradeVerdict {
+	if !preTradeLimits.Enabled {
+		return PreTradeVerdict{MarginUSDT: marginUSDT}
+	}
+	snapshot, err := fetchMarketSnapshot(symbol, job.Listing.DetectedAt, preTradeLimits.DepthBandPct)
+	if err != nil {
+		return preTradeLimits.unavailable(symbol, marginUSDT, err)
+	}
+
+	verdict := preTradeLimits.Check(snapshot, marginUSDT, job.User.Leverage)
+	if len(verdict.Reasons) > 0 {
+		log.Printf("🧪 Pre-trade %s for user %d (spread %.2f%%, depth %.0f USDT, pre-move %.1f%%): %s",
+			symbol, job.User.UserID, snapshot.SpreadPct, snapshot.AskDepth, snapshot.PreMovePct, strings.Join(verdict.Reasons, "; "))
+	}
+	return verdict
+}
+
+// unavailable is the verdict when the market snapshot could not be fetched
+func (l PreTradeLimits) unavailable(symbol string, marginUSDT float64, err error) PreTradeVerdict {
+	if l.FailOpen {
+		log.Printf("⚠️ Pre-trade checks unavailable for %s, trading unchecked: %v", symbol, err)
+		return PreTradeVerdict{MarginUSDT: marginUSDT, Reasons: []string{"piyasa verisi alınamadı, kontrolsüz işlem açıldı"}}
+	}
+	log.Printf("⚠️ Pre-trade checks unavailable for %s, skipping: %v", symbol, err)
+	return PreTradeVerdict{Skip: true, MarginUSDT: marginUSDT, Reasons: []string{fmt.Sprintf("piyasa verisi alınamadı: %v", err)}}
+}
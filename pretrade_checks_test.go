@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestPreTradeLimitsCheck(t *testing.T) {
+	limits := PreTradeLimits{
+		Enabled:       true,
+		MaxSpreadPct:  1,
+		DepthBandPct:  2,
+		MinDepthUSDT:  2000,
+		MaxDepthShare: 0.5,
+		MaxPreMovePct: 30,
+	}
+	healthy := MarketSnapshot{BestBid: 0.999, BestAsk: 1, SpreadPct: 0.1, AskDepth: 100000}
+
+	tests := []struct {
+		name       string
+		snapshot   func(s *MarketSnapshot)
+		margin     float64
+		leverage   int
+		wantSkip   bool
+		wantMargin float64
+		reasons    int
+	}{
+		{"healthy book trades unchanged", nil, 100, 10, false, 100, 0},
+		{"wide spread skips", func(s *MarketSnapshot) { s.SpreadPct = 2.5 }, 100, 10, true, 100, 1},
+		{"thin band skips", func(s *MarketSnapshot) { s.AskDepth = 1500 }, 100, 10, true, 100, 1},
+		{"pre-move skips", func(s *MarketSnapshot) { s.RefPrice = 0.5; s.PreMovePct = 100 }, 100, 10, true, 100, 1},
+		{"pre-move ignored without reference", func(s *MarketSnapshot) { s.PreMovePct = 100 }, 100, 10, false, 100, 0},
+		{"every failed check is reported", func(s *MarketSnapshot) { s.SpreadPct = 5; s.AskDepth = 10 }, 100, 10, true, 100, 2},
+		{"empty book skips", func(s *MarketSnapshot) { *s = MarketSnapshot{} }, 100, 10, true, 100, 1},
+		{"deep enough is not downsized", func(s *MarketSnapshot) { s.AskDepth = 2000 }, 100, 10, false, 100, 0},
+		{"thin book downsizes", func(s *MarketSnapshot) { s.AskDepth = 3000 }, 200, 10, false, 150, 1},
+		{"zero leverage is not sized", func(s *MarketSnapshot) { s.AskDepth = 3000 }, 200, 0, false, 200, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snapshot := healthy
+			if tt.snapshot != nil {
+				tt.snapshot(&snapshot)
+			}
+			verdict := limits.Check(&snapshot, tt.margin, tt.leverage)
+			if verdict.Skip != tt.wantSkip {
+				t.Errorf("Skip = %v, want %v (%v)", verdict.Skip, tt.wantSkip, verdict.Reasons)
+			}
+			if math.Abs(verdict.MarginUSDT-tt.wantMargin) > 1e-9 {
+				t.Errorf("MarginUSDT = %v, want %v", verdict.MarginUSDT, tt.wantMargin)
+			}
+			if len(verdict.Reasons) != tt.reasons {
+				t.Errorf("reasons = %q, want %d", verdict.Reasons, tt.reasons)
+			}
+		})
+	}
+
+	// An empty book is skipped even when no depth floor is configured
+	limits.MinDepthUSDT = 0
+	if verdict := limits.Check(&MarketSnapshot{}, 100, 10); !verdict.Skip {
+		t.Errorf("empty book with no depth floor traded: %+v", verdict)
+	}
+}
+
+func TestPreTradeLimitsUnavailable(t *testing.T) {
+	err := errors.New("timeout")
+
+	closed := PreTradeLimits{}.unavailable("FOOUSDT", 50, err)
+	if !closed.Skip || len(closed.Reasons) != 1 {
+		t.Errorf("fail-closed verdict = %+v, want skip with a reason", closed)
+	}
+
+	open := PreTradeLimits{FailOpen: true}.unavailable("FOOUSDT", 50, err)
+	if open.Skip || open.MarginUSDT != 50 || len(open.Reasons) != 1 {
+		t.Errorf("fail-open verdict = %+v, want trade at full margin with a reason", open)
+	}
+}
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptrace"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// Keep idle connections well past the slowest per-proxy poll interval so the hot path stays warm
+const (
+	proxyIdleConnTimeout  = 5 * time.Minute
+	proxyRequestTimeout   = 10 * time.Second
+	proxyStatsLogInterval = 5 * time.Minute
+)
+
+// ProxyConnStats is a snapshot of one proxy client's connection reuse counters
+type ProxyConnStats struct {
+	ProxyID       string
+	ProxyName     string
+	Profile       string
+	Requests      int64
+	Errors        int64
+	NewConns      int64
+	ReusedConns   int64
+	TLSHandshakes int64
+	TLSResumed    int64
+	Recycles      int64
+	LastError     string
+}
+
+// ReuseRate is the share of requests served on an already-open connection
+func (s ProxyConnStats) ReuseRate() float64 {
+	total := s.NewConns + s.ReusedConns
+	if total == 0 {
+		return 0
+	}
+	return float64(s.ReusedConns) / float64(total)
+}
+
+// proxyClient is the long-lived HTTP client for one proxy
+type proxyClient struct {
+	config    ProxyConfig
+	profile   ClientProfile
+	client    *http.Client
+	transport *http.Transport
+
+	requests      int64
+	errors        int64
+	newConns      int64
+	reusedConns   int64
+	tlsHandshakes int64
+	tlsResumed    int64
+	recycles      int64
+
+	lastErrMu sync.Mutex
+	lastErr   string
+}
+
+// ProxyClientPool holds one persistent client per proxy ID
+type ProxyClientPool struct {
+	mu      sync.RWMutex
+	clients map[string]*proxyClient
+	order   []string // Proxy IDs in list order, for stats
+}
+
+// NewProxyClientPool builds a client for every proxy; broken proxy URLs are logged and skipped
+func NewProxyClientPool(proxies []ProxyConfig) *ProxyClientPool {
+	pool := &ProxyClientPool{clients: make(map[string]*proxyClient)}
+	pool.Sync(proxies)
+	log.Printf("🔌 Proxy client pool ready: %d/%d clients", len(pool.clients), len(proxies))
+	return pool
+}
+
+// Sync reconciles the pool with a new proxy list: unchanged proxies keep their warm
+// client, changed ones are rebuilt and removed ones are closed.
+func (p *ProxyClientPool) Sync(proxies []ProxyConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	profiles := enabledClientProfiles()
+	clients := make(map[string]*proxyClient, len(proxies))
+	order := make([]string, 0, len(proxies))
+	for _, config := range proxies {
+		id := config.ID()
+		if existing, exists := p.clients[id]; exists && existing.config.URL == config.URL {
+			existing.config = config
+			clients[id] = existing
+			order = append(order, id)
+			continue
+		}
+
+		pc, err := newProxyClient(config, profileForProxy(id, profiles))
+		if err != nil {
+			log.Printf("❌ %s: Client creation failed: %v", config.DisplayName(), err)
+			continue
+		}
+		clients[id] = pc
+		order = append(order, id)
+	}
+
+	for id, pc := range p.clients {
+		if clients[id] != pc {
+			pc.transport.CloseIdleConnections()
+		}
+	}
+
+	p.clients = clients
+	p.order = order
+}
+
+func newProxyClient(config ProxyConfig, profile ClientProfile) (*proxyClient, error) {
+	parsedURL, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("proxy URL'si ayrıştırılamadı: %w", err)
+	}
+
+	baseDialer := &net.Dialer{Timeout: 5 * time.Second, KeepAlive: 30 * time.Second}
+	var dialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+
+	switch config.Protocol {
+	case ProxyProtocolHTTP, ProxyProtocolHTTPS:
+		// CONNECT tunnel opened by the dialer; with Transport.Proxy the Transport would run
+		// its own crypto/tls handshake inside the tunnel instead of the profile's ClientHello
+		dialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return connectTunnel(ctx, baseDialer, parsedURL, addr)
+		}
+
+	case ProxyProtocolDirect:
+		// Direct egress bound to a local source IP (IPv4 or IPv6)
+		localIP := net.ParseIP(parsedURL.Hostname())
+		if localIP == nil {
+			return nil, fmt.Errorf("geçersiz kaynak IP: %s", parsedURL.Hostname())
+		}
+		baseDialer.LocalAddr = &net.TCPAddr{IP: localIP}
+		dialContext = baseDialer.DialContext
+
+	default:
+		dialer, err := proxy.FromURL(parsedURL, baseDialer)
+		if err != nil {
+			return nil, fmt.Errorf("proxy dialer oluşturulamadı: %w", err)
+		}
+		dialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if contextDialer, ok := dialer.(proxy.ContextDialer); ok {
+				return contextDialer.DialContext(ctx, network, addr)
+			}
+			return dialer.Dial(network, addr)
+		}
+	}
+
+	transport := &http.Transport{
+		DialContext:         dialContext,
+		DisableKeepAlives:   false, // Enable keep-alive like real browsers
+		MaxIdleConns:        4,
+		MaxIdleConnsPerHost: 2,
+		IdleConnTimeout:     proxyIdleConnTimeout,
+		TLSHandshakeTimeout: 5 * time.Second,
+	}
+	// TLS ClientHello, ALPN and HTTP/2 settings follow the proxy's browser profile
+	profile.ConfigureTransport(transport)
+
+	// Cookie jar lives as long as the client, like a browser session
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, fmt.Errorf("cookie jar oluşturulamadı: %w", err)
+	}
+
+	return &proxyClient{
+		config:    config,
+		profile:   profile,
+		transport: transport,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   proxyRequestTimeout,
+			Jar:       jar,
+		},
+	}, nil
+}
+
+// connectTunnel opens a CONNECT tunnel to addr through an HTTP or HTTPS proxy; credentials
+// in the proxy URL are sent as Proxy-Authorization. Errors name only the proxy host, never
+// the credentials.
+func connectTunnel(ctx context.Context, dialer *net.Dialer, proxyURL *url.URL, addr string) (net.Conn, error) {
+	proxyAddr := proxyURL.Host
+	if proxyURL.Port() == "" {
+		port := "80"
+		if proxyURL.Scheme == ProxyProtocolHTTPS {
+			port = "443"
+		}
+		proxyAddr = net.JoinHostPort(proxyURL.Hostname(), port)
+	}
+
+	conn, err := dialer.DialContext(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+	if proxyURL.Scheme == ProxyProtocolHTTPS {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: proxyURL.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("proxy %s TLS: %w", proxyURL.Hostname(), err)
+		}
+		conn = tlsConn
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if user := proxyURL.User; user != nil {
+		password, _ := user.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s CONNECT: %w", proxyURL.Hostname(), err)
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s CONNECT: %w", proxyURL.Hostname(), err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s CONNECT: %s", proxyURL.Hostname(), resp.Status)
+	}
+	if reader.Buffered() > 0 {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s CONNECT: unexpected data after response", proxyURL.Hostname())
+	}
+	return conn, nil
+}
+
+// Do sends req through the proxy's persistent client with its profile's browser headers,
+// recording connection reuse. On a transport error the proxy's idle connections are recycled so the next poll starts clean.
+func (p *ProxyClientPool) Do(proxyID string, req *http.Request) (*http.Response, error) {
+	p.mu.RLock()
+	pc, exists := p.clients[proxyID]
+	p.mu.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("no client for proxy %s", proxyID)
+	}
+
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if info.Reused {
+				atomic.AddInt64(&pc.reusedConns, 1)
+			} else {
+				atomic.AddInt64(&pc.newConns, 1)
+			}
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			if err != nil {
+				return
+			}
+			atomic.AddInt64(&pc.tlsHandshakes, 1)
+			if state.DidResume {
+				atomic.AddInt64(&pc.tlsResumed, 1)
+			}
+		},
+	}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+	pc.profile.ApplyHeaders(req)
+
+	atomic.AddInt64(&pc.requests, 1)
+	resp, err := pc.client.Do(req)
+	if err != nil {
+		atomic.AddInt64(&pc.errors, 1)
+		pc.lastErrMu.Lock()
+		pc.lastErr = err.Error()
+		pc.lastErrMu.Unlock()
+		p.Recycle(proxyID)
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Recycle drops the proxy's idle connections; TLS sessions stay cached for fast resumption
+func (p *ProxyClientPool) Recycle(proxyID string) {
+	p.mu.RLock()
+	pc, exists := p.clients[proxyID]
+	p.mu.RUnlock()
+	if !exists {
+		return
+	}
+
+	pc.transport.CloseIdleConnections()
+	atomic.AddInt64(&pc.recycles, 1)
+}
+
+// Stats returns a snapshot of every proxy client's counters, in proxy list order
+func (p *ProxyClientPool) Stats() []ProxyConnStats {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	stats := make([]ProxyConnStats, 0, len(p.order))
+	for _, id := range p.order {
+		pc := p.clients[id]
+		pc.lastErrMu.Lock()
+		lastErr := pc.lastErr
+		pc.lastErrMu.Unlock()
+
+		stats = append(stats, ProxyConnStats{
+			ProxyID:       id,
+			ProxyName:     pc.config.DisplayName(),
+			Profile:       pc.profile.Name,
+			Requests:      atomic.LoadInt64(&pc.requests),
+			Errors:        atomic.LoadInt64(&pc.errors),
+			NewConns:      atomic.LoadInt64(&pc.newConns),
+			ReusedConns:   atomic.LoadInt64(&pc.reusedConns),
+			TLSHandshakes: atomic.LoadInt64(&pc.tlsHandshakes),
+			TLSResumed:    atomic.LoadInt64(&pc.tlsResumed),
+			Recycles:      atomic.LoadInt64(&pc.recycles),
+			LastError:     lastErr,
+		})
+	}
+	return stats
+}
+
+// logStatsLoop periodically logs connection reuse per proxy until stop is closed
+func (p *ProxyClientPool) logStatsLoop(stop <-chan struct{}) {
+	ticker := time.NewTicker(proxyStatsLogInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+
+		log.Printf("🔌 PROXY CONNECTION STATS:")
+		for _, s := range p.Stats() {
+			log.Printf("   • %s [%s]: %d req, %d err, reuse %.0f%% (%d new / %d reused), TLS %d (%d resumed), %d recycles",
+				s.ProxyName, s.Profile, s.Requests, s.Errors, s.ReuseRate()*100, s.NewConns, s.ReusedConns,
+				s.TLSHandshakes, s.TLSResumed, s.Recycles)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math"
+	"math/rand"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	json "github.com/json-iterator/go"
+)
+
+// ProxyOutcome is the result of one Upbit poll through a proxy
+type ProxyOutcome struct {
+	StatusCode int           // 0 when the request failed before a response
+	Latency    time.Duration // Time to response headers
+	Err        error
+}
+
+// Healthy reports whether the poll got a usable answer (200 or 304)
+func (o ProxyOutcome) Healthy() bool {
+	return o.Err == nil && (o.StatusCode == http.StatusOK || o.StatusCode == http.StatusNotModified)
+}
+
+// ProxyScheduler decides which proxy polls next and how long failing proxies sit out
+type ProxyScheduler interface {
+	// Pick chooses one of the available proxy IDs
+	Pick(available []string) string
+	// Report records a poll outcome and returns how long to quarantine the proxy (0 = none)
+	Report(proxyID string, outcome ProxyOutcome) time.Duration
+	// Scores returns the current per-proxy health, in proxy list order
+	Scores() []ProxyScore
+	// SetProxies adopts a reloaded proxy list, keeping scores of proxies that remain
+	SetProxies(proxies []ProxyConfig)
+	// Save persists learned scores so restarts keep them
+	Save() error
+}
+
+// ProxyScore is the rolling health of one proxy
+type ProxyScore struct {
+	Key                 string    `json:"key"` // Proxy ID (see proxyKey), stable across restarts
+	Name                string    `json:"name"`
+	Weight              float64   `json:"-"`               // Configured weight from the proxy list
+	LatencyMs           float64   `json:"latency_ms"`      // EWMA of healthy response times
+	ErrorRate           float64   `json:"error_rate"`      // EWMA of network errors / unexpected statuses
+	RateLimitRate       float64   `json:"rate_limit_rate"` // EWMA of 429 responses
+	Samples             int64     `json:"samples"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	QuarantinedUntil    time.Time `json:"quarantined_until"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+// SelectionWeight is the selection weight: faster and healthier proxies are picked more often
+func (s *ProxyScore) SelectionWeight() float64 {
+	latency := s.LatencyMs
+	if s.Samples == 0 || latency <= 0 {
+		latency = schedulerUnknownLatencyMs // Optimistic so new proxies get explored
+	}
+	weight := s.Weight
+	if weight <= 0 {
+		weight = 1
+	}
+	health := (1 - s.ErrorRate) * (1 - s.RateLimitRate)
+	return weight * health * health * 1000 / latency
+}
+
+const (
+	schedulerEWMAAlpha        = 0.2
+	schedulerUnknownLatencyMs = 250.0
+	schedulerMinWeightShare   = 0.05 // Every proxy keeps at least 5% of the best weight
+	schedulerErrorBaseBackoff = 5 * time.Second
+	schedulerErrorMaxBackoff  = 5 * time.Minute
+	schedulerLimitBaseBackoff = 30 * time.Second
+	schedulerLimitMaxBackoff  = 10 * time.Minute
+)
+
+// AdaptiveScheduler weights proxy selection by EWMA latency, error and 429 rates
+// and quarantines failing proxies with exponential backoff. Scores persist to scoresFile.
+type AdaptiveScheduler struct {
+	mu         sync.Mutex
+	scores     map[string]*ProxyScore
+	order      []string // Proxy IDs in list order
+	scoresFile string
+	rng        *rand.Rand
+}
+
+// NewAdaptiveScheduler creates scores for the given proxies, restoring saved ones by proxy ID
+func NewAdaptiveScheduler(proxies []ProxyConfig, scoresFile string) *AdaptiveScheduler {
+	as := &AdaptiveScheduler{
+		scoresFile: scoresFile,
+		rng:        rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+
+	as.scores = as.load()
+	restored := 0
+	for _, config := range proxies {
+		if _, exists := as.scores[config.ID()]; exists {
+			restored++
+		}
+	}
+	as.SetProxies(proxies)
+
+	log.Printf("📈 Proxy scheduler: restored scores for %d/%d proxies from %s", restored, len(proxies), scoresFile)
+	return as
+}
+
+// SetProxies keeps scores for proxies still in the list, adds fresh ones and drops the rest
+func (as *AdaptiveScheduler) SetProxies(proxies []ProxyConfig) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	scores := make(map[string]*ProxyScore, len(proxies))
+	order := make([]string, 0, len(proxies))
+	for _, config := range proxies {
+		id := config.ID()
+		score, exists := as.scores[id]
+		if !exists {
+			score = &ProxyScore{Key: id}
+		}
+		score.Name = config.DisplayName()
+		score.Weight = config.Weight
+		scores[id] = score
+		order = append(order, id)
+	}
+	as.scores = scores
+	as.order = order
+}
+
+func (as *AdaptiveScheduler) load() map[string]*ProxyScore {
+	saved := make(map[string]*ProxyScore)
+
+	data, err := ioutil.ReadFile(as.scoresFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("⚠️ Could not read %s: %v", as.scoresFile, err)
+		}
+		return saved
+	}
+
+	var scores []*ProxyScore
+	if err := json.Unmarshal(data, &scores); err != nil {
+		log.Printf("⚠️ Could not parse %s: %v", as.scoresFile, err)
+		return saved
+	}
+	for _, score := range scores {
+		saved[score.Key] = score
+	}
+	return saved
+}
+
+// Pick does a weighted random choice over the available proxies
+func (as *AdaptiveScheduler) Pick(available []string) string {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	weights := make([]float64, len(available))
+	maxWeight := 0.0
+	for i, proxyID := range available {
+		if score, exists := as.scores[proxyID]; exists {
+			weights[i] = score.SelectionWeight()
+		}
+		maxWeight = math.Max(maxWeight, weights[i])
+	}
+
+	total := 0.0
+	for i := range weights {
+		weights[i] = math.Max(weights[i], maxWeight*schedulerMinWeightShare)
+		total += weights[i]
+	}
+	if total <= 0 {
+		return available[as.rng.Intn(len(available))]
+	}
+
+	target := as.rng.Float64() * total
+	for i, weight := range weights {
+		target -= weight
+		if target <= 0 {
+			return available[i]
+		}
+	}
+	return available[len(available)-1]
+}
+
+// Report updates the proxy's rolling rates and returns its quarantine (0 when healthy)
+func (as *AdaptiveScheduler) Report(proxyID string, outcome ProxyOutcome) time.Duration {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	score, exists := as.scores[proxyID]
+	if !exists {
+		return 0
+	}
+
+	ewma := func(current, sample float64) float64 {
+		if score.Samples == 0 {
+			return sample
+		}
+		return current + schedulerEWMAAlpha*(sample-current)
+	}
+
+	rateLimited := outcome.StatusCode == http.StatusTooManyRequests
+	failed := !outcome.Healthy() && !rateLimited
+
+	score.ErrorRate = ewma(score.ErrorRate, boolToFloat(failed))
+	score.RateLimitRate = ewma(score.RateLimitRate, boolToFloat(rateLimited))
+	if outcome.Healthy() {
+		latencyMs := float64(outcome.Latency) / float64(time.Millisecond)
+		if score.LatencyMs <= 0 {
+			score.LatencyMs = latencyMs
+		} else {
+			score.LatencyMs += schedulerEWMAAlpha * (latencyMs - score.LatencyMs)
+		}
+	}
+	score.Samples++
+	score.UpdatedAt = time.Now()
+
+	if outcome.Healthy() {
+		score.ConsecutiveFailures = 0
+		return 0
+	}
+
+	score.ConsecutiveFailures++
+	base, maxBackoff := schedulerErrorBaseBackoff, schedulerErrorMaxBackoff
+	if rateLimited {
+		base, maxBackoff = schedulerLimitBaseBackoff, schedulerLimitMaxBackoff
+	}
+	quarantine := base << uint(min(score.ConsecutiveFailures-1, 10))
+	if quarantine > maxBackoff {
+		quarantine = maxBackoff
+	}
+	score.QuarantinedUntil = time.Now().Add(quarantine)
+	return quarantine
+}
+
+// Scores returns a copy of every proxy's score, in proxy list order
+func (as *AdaptiveScheduler) Scores() []ProxyScore {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	scores := make([]ProxyScore, 0, len(as.order))
+	for _, id := range as.order {
+		scores = append(scores, *as.scores[id])
+	}
+	return scores
+}
+
+// Save writes the scores to disk so a restart keeps what was learned
+func (as *AdaptiveScheduler) Save() error {
+	data, err := json.MarshalIndent(as.Scores(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal proxy scores: %w", err)
+	}
+	if err := ioutil.WriteFile(as.scoresFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", as.scoresFile, err)
+	}
+	return nil
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
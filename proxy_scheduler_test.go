@@ -0,0 +1,146 @@
+package main
+
+import (
+	"errors"
+	"math/rand"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestScheduler(t *testing.T, urls ...string) (*AdaptiveScheduler, []string) {
+	t.Helper()
+	var proxies []ProxyConfig
+	var ids []string
+	for _, u := range urls {
+		config := ProxyConfig{Name: u, URL: u, Weight: 1}
+		proxies = append(proxies, config)
+		ids = append(ids, config.ID())
+	}
+	as := NewAdaptiveScheduler(proxies, filepath.Join(t.TempDir(), "scores.json"))
+	as.rng = rand.New(rand.NewSource(1))
+	return as, ids
+}
+
+func TestSchedulerQuarantineBackoff(t *testing.T) {
+	as, ids := newTestScheduler(t, "socks5://a:1080")
+	id := ids[0]
+	failure := ProxyOutcome{Err: errors.New("connection reset")}
+	limited := ProxyOutcome{StatusCode: http.StatusTooManyRequests}
+	healthy := ProxyOutcome{StatusCode: http.StatusOK, Latency: 80 * time.Millisecond}
+
+	steps := []struct {
+		name    string
+		outcome ProxyOutcome
+		want    time.Duration
+	}{
+		{"first error", failure, schedulerErrorBaseBackoff},
+		{"second error doubles", failure, 2 * schedulerErrorBaseBackoff},
+		{"third error doubles", failure, 4 * schedulerErrorBaseBackoff},
+		{"healthy clears", healthy, 0},
+		{"error after recovery restarts", failure, schedulerErrorBaseBackoff},
+		{"healthy clears again", healthy, 0},
+		{"429 uses the rate-limit base", limited, schedulerLimitBaseBackoff},
+		{"second 429 doubles", limited, 2 * schedulerLimitBaseBackoff},
+	}
+	for _, step := range steps {
+		if got := as.Report(id, step.outcome); got != step.want {
+			t.Fatalf("%s: quarantine = %v, want %v", step.name, got, step.want)
+		}
+	}
+}
+
+func TestSchedulerQuarantineCapped(t *testing.T) {
+	as, ids := newTestScheduler(t, "socks5://a:1080", "socks5://b:1080")
+	var got time.Duration
+	for i := 0; i < 30; i++ {
+		got = as.Report(ids[0], ProxyOutcome{StatusCode: http.StatusBadGateway})
+	}
+	if got != schedulerErrorMaxBackoff {
+		t.Errorf("error quarantine = %v, want cap %v", got, schedulerErrorMaxBackoff)
+	}
+	for i := 0; i < 30; i++ {
+		got = as.Report(ids[1], ProxyOutcome{StatusCode: http.StatusTooManyRequests})
+	}
+	if got != schedulerLimitMaxBackoff {
+		t.Errorf("429 quarantine = %v, want cap %v", got, schedulerLimitMaxBackoff)
+	}
+}
+
+func TestSchedulerReportUnknownProxy(t *testing.T) {
+	as, _ := newTestScheduler(t, "socks5://a:1080")
+	if got := as.Report("socks5://missing:1080", ProxyOutcome{Err: errors.New("x")}); got != 0 {
+		t.Errorf("unknown proxy quarantine = %v, want 0", got)
+	}
+}
+
+func TestSchedulerSelectionWeightOrdering(t *testing.T) {
+	as, ids := newTestScheduler(t, "socks5://fast:1080", "socks5://slow:1080", "socks5://flaky:1080")
+	fast, slow, flaky := ids[0], ids[1], ids[2]
+	for i := 0; i < 20; i++ {
+		as.Report(fast, ProxyOutcome{StatusCode: http.StatusOK, Latency: 50 * time.Millisecond})
+		as.Report(slow, ProxyOutcome{StatusCode: http.StatusNotModified, Latency: 400 * time.Millisecond})
+		outcome := ProxyOutcome{StatusCode: http.StatusOK, Latency: 50 * time.Millisecond}
+		if i%2 == 0 {
+			outcome = ProxyOutcome{StatusCode: http.StatusServiceUnavailable}
+		}
+		as.Report(flaky, outcome)
+	}
+
+	weights := make(map[string]float64)
+	for _, score := range as.Scores() {
+		weights[score.Key] = score.SelectionWeight()
+	}
+	if !(weights[fast] > weights[slow]) {
+		t.Errorf("fast weight %.2f should exceed slow %.2f", weights[fast], weights[slow])
+	}
+	if !(weights[fast] > weights[flaky]) {
+		t.Errorf("fast weight %.2f should exceed flaky %.2f", weights[fast], weights[flaky])
+	}
+
+	picks := make(map[string]int)
+	for i := 0; i < 5000; i++ {
+		picks[as.Pick(ids)]++
+	}
+	if !(picks[fast] > picks[slow] && picks[fast] > picks[flaky]) {
+		t.Errorf("fast proxy should be picked most: %v", picks)
+	}
+	for _, id := range ids {
+		if picks[id] == 0 {
+			t.Errorf("%s was never picked; every proxy keeps a minimum share", id)
+		}
+	}
+}
+
+func TestSchedulerPickOnlyAvailable(t *testing.T) {
+	as, ids := newTestScheduler(t, "socks5://a:1080", "socks5://b:1080", "socks5://c:1080")
+	for i := 0; i < 200; i++ {
+		if got := as.Pick(ids[1:2]); got != ids[1] {
+			t.Fatalf("Pick returned %s outside the available set", got)
+		}
+	}
+}
+
+func TestSchedulerScoresSurviveRestartAndReload(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "scores.json")
+	proxies := []ProxyConfig{{Name: "a", URL: "socks5://a:1080"}, {Name: "b", URL: "socks5://b:1080"}}
+
+	as := NewAdaptiveScheduler(proxies, file)
+	as.Report(proxies[0].ID(), ProxyOutcome{StatusCode: http.StatusOK, Latency: 120 * time.Millisecond})
+	if err := as.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := NewAdaptiveScheduler(proxies, file)
+	scores := restored.Scores()
+	if scores[0].Samples != 1 || scores[0].LatencyMs != 120 {
+		t.Errorf("restored score = %+v, want 1 sample at 120ms", scores[0])
+	}
+
+	restored.SetProxies(proxies[:1])
+	if got := len(restored.Scores()); got != 1 {
+		t.Errorf("after reload %d scores, want 1", got)
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	rateIncreaseInterval   = 10 * time.Second // Clean period before each additive step
+	rateBurstWindow        = 10 * time.Second
+	rateBurstThreshold     = 2 // 429s within the window that count as a burst
+	rateDecreaseCooldown   = 5 * time.Second
+	rateProxySlack         = 0.8 // Per-proxy interval is 80% of a full rotation so the scheduler keeps a choice
+	rateJitter             = 0.2
+	rateEffectiveWindow    = time.Minute
+	defaultRateInitial     = 3.0
+	defaultRateMin         = 0.5
+	defaultRateMax         = 10.0
+	defaultRateIncreaseBy  = 0.25
+	rateNoProxyRetryPeriod = 50 * time.Millisecond
+)
+
+// RateStats is a snapshot of the controller for operators
+type RateStats struct {
+	TargetRate     float64 // req/s the controller is aiming for
+	EffectiveRate  float64 // req/s actually sent over the last minute
+	MinRate        float64
+	MaxRate        float64
+	Step           float64
+	Requests       int64
+	RateLimited    int64
+	Decreases      int64
+	LastDecrease   time.Time
+	LastIncrease   time.Time
+	HealthyProxies int
+	ProxyInterval  time.Duration
+}
+
+// RateController sets the aggregate Upbit request rate with AIMD:
+// +step req/s after every clean interval, halved on a burst of 429s.
+type RateController struct {
+	mu        sync.Mutex
+	rate      float64
+	minRate   float64
+	maxRate   float64
+	step      float64
+	recent429 []time.Time
+	cleanFrom time.Time // Start of the current clean (429-free) period
+	lastRaise time.Time
+	lastCut   time.Time
+
+	requests    int64
+	rateLimited int64
+	decreases   int64
+
+	windowStart   time.Time
+	windowCount   int64
+	effectiveRate float64
+}
+
+// NewRateController reads UPBIT_RATE_INITIAL/MIN/MAX/STEP (req/s)
+func NewRateController() *RateController {
+	minRate := envFloat("UPBIT_RATE_MIN", defaultRateMin)
+	maxRate := envFloat("UPBIT_RATE_MAX", defaultRateMax)
+	if maxRate < minRate {
+		log.Printf("⚠️ UPBIT_RATE_MAX < UPBIT_RATE_MIN, using %.2f for both", minRate)
+		maxRate = minRate
+	}
+	rate := clampFloat(envFloat("UPBIT_RATE_INITIAL", defaultRateInitial), minRate, maxRate)
+
+	now := time.Now()
+	return &RateController{
+		rate:        rate,
+		minRate:     minRate,
+		maxRate:     maxRate,
+		step:        envFloat("UPBIT_RATE_STEP", defaultRateIncreaseBy),
+		cleanFrom:   now,
+		windowStart: now,
+	}
+}
+
+// envFloat parses a positive float env var, falling back to def
+func envFloat(name string, def float64) float64 {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil || value <= 0 {
+		log.Printf("⚠️ Invalid %s '%s', using %.2f", name, raw, def)
+		return def
+	}
+	return value
+}
+
+func clampFloat(value, lo, hi float64) float64 {
+	if value < lo {
+		return lo
+	}
+	if value > hi {
+		return hi
+	}
+	return value
+}
+
+// Report feeds one poll outcome into the AIMD loop
+func (rc *RateController) Report(outcome ProxyOutcome) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	now := time.Now()
+	rc.requests++
+	rc.windowCount++
+	if elapsed := now.Sub(rc.windowStart); elapsed >= rateEffectiveWindow {
+		rc.effectiveRate = float64(rc.windowCount) / elapsed.Seconds()
+		rc.windowStart, rc.windowCount = now, 0
+	}
+
+	if outcome.StatusCode == http.StatusTooManyRequests {
+		rc.rateLimited++
+		rc.cleanFrom = now // Any 429 restarts the clean period
+
+		recent := rc.recent429[:0]
+		for _, t := range rc.recent429 {
+			if now.Sub(t) < rateBurstWindow {
+				recent = append(recent, t)
+			}
+		}
+		rc.recent429 = append(recent, now)
+
+		if len(rc.recent429) >= rateBurstThreshold && now.Sub(rc.lastCut) >= rateDecreaseCooldown {
+			old := rc.rate
+			rc.rate = clampFloat(rc.rate/2, rc.minRate, rc.maxRate)
+			rc.lastCut = now
+			rc.decreases++
+			rc.recent429 = rc.recent429[:0]
+			log.Printf("📉 Upbit rate halved after 429 burst: %.2f → %.2f req/s", old, rc.rate)
+		}
+		return
+	}
+
+	if outcome.Healthy() && now.Sub(rc.cleanFrom) >= rateIncreaseInterval && rc.rate < rc.maxRate {
+		rc.rate = clampFloat(rc.rate+rc.step, rc.minRate, rc.maxRate)
+		rc.cleanFrom = now
+		rc.lastRaise = now
+	}
+}
+
+// Rate returns the current target rate in req/s
+func (rc *RateController) Rate() float64 {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return rc.rate
+}
+
+// Interval is the jittered gap between two requests across the whole pool
+func (rc *RateController) Interval() time.Duration {
+	base := float64(time.Second) / rc.Rate()
+	return time.Duration(base * (1 - rateJitter + 2*rateJitter*rand.Float64()))
+}
+
+// ProxyInterval is how long one proxy rests after a request so poolSize proxies sustain the target rate
+func (rc *RateController) ProxyInterval(poolSize int) time.Duration {
+	if poolSize < 1 {
+		poolSize = 1
+	}
+	return time.Duration(float64(poolSize) / rc.Rate() * rateProxySlack * float64(time.Second))
+}
+
+// WorkerInterval is the period of one per-proxy worker so poolSize workers together sustain the target rate
+func (rc *RateController) WorkerInterval(poolSize int) time.Duration {
+	if poolSize < 1 {
+		poolSize = 1
+	}
+	return time.Duration(float64(poolSize) / rc.Rate() * float64(time.Second))
+}
+
+// Stats returns a snapshot for logging and the /rate command
+func (rc *RateController) Stats(healthyProxies int) RateStats {
+	proxyInterval := rc.ProxyInterval(healthyProxies)
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	effective := rc.effectiveRate
+	if elapsed := time.Since(rc.windowStart); effective == 0 && elapsed >= 5*time.Second {
+		effective = float64(rc.windowCount) / elapsed.Seconds()
+	}
+
+	return RateStats{
+		TargetRate:     rc.rate,
+		EffectiveRate:  effective,
+		MinRate:        rc.minRate,
+		MaxRate:        rc.maxRate,
+		Step:           rc.step,
+		Requests:       rc.requests,
+		RateLimited:    rc.rateLimited,
+		Decreases:      rc.decreases,
+		LastDecrease:   rc.lastCut,
+		LastIncrease:   rc.lastRaise,
+		HealthyProxies: healthyProxies,
+		ProxyInterval:  proxyInterval,
+	}
+}
+
+// String renders the stats for logs
+func (s RateStats) String() string {
+	return fmt.Sprintf("target %.2f req/s, effective %.2f req/s, %d healthy proxies @ %v, %d/%d rate limited, %d cuts",
+		s.TargetRate, s.EffectiveRate, s.HealthyProxies, s.ProxyInterval.Round(time.Millisecond),
+		s.RateLimited, s.Requests, s.Decreases)
+}
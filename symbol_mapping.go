@@ -0,0 +1,311 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	json "github.com/json-iterator/go"
+)
+
+const (
+	defaultSymbolOverridesFile = "symbol_overrides.json"
+	contractCacheTTL           = 5 * time.Minute
+	contractMissRefresh        = 10 * time.Second // A miss re-fetches the list at most this often
+	contractFetchTimeout       = 3 * time.Second
+)
+
+// Name check policies for contracts no curated rule confirms (SYMBOL_NAME_CHECK)
+const (
+	NameCheckWarn   = "warn"   // Trade, but tell users the project was not verified (default)
+	NameCheckStrict = "strict" // Refuse: same ticker may be a different project on Bitget
+)
+
+// contractMultipliers are the denominations Bitget uses for low-priced coins (1000PEPEUSDT)
+var contractMultipliers = []string{"1000", "10000", "1000000"}
+
+// symbolMapper is shared by all trades; the contract list is cached process-wide
+var symbolMapper = NewSymbolMapper()
+
+// SymbolOverride is one curated rule from symbol_overrides.json
+type SymbolOverride struct {
+	Ticker string   `json:"ticker"`           // Upbit ticker from the notice
+	Symbol string   `json:"symbol,omitempty"` // Bitget contract, e.g. 1000PEPEUSDT
+	Names  []string `json:"names,omitempty"`  // Title must contain one of these (project name, Korean name)
+	Block  bool     `json:"block,omitempty"`  // Never trade this ticker (e.g. Bitget ticker is another project)
+	Note   string   `json:"note,omitempty"`   // Shown to users when the rule is applied
+}
+
+// SymbolMapping is how an Upbit ticker is traded on Bitget
+type SymbolMapping struct {
+	Ticker   string
+	Symbol   string // Bitget USDT-M contract
+	Source   string // "override" or "contract"
+	Note     string
+	Verified bool         // Project confirmed by a curated rule (symbol or names matching the title)
+	Contract ContractInfo // Zero when the override names a contract Bitget didn't return
+}
+
+// SymbolMapper resolves Upbit tickers to Bitget contracts
+type SymbolMapper struct {
+	overridesFile string
+	nameCheck     string // NameCheckWarn or NameCheckStrict
+
+	mu          sync.Mutex
+	overrides   []SymbolOverride
+	overridesAt time.Time // Override file mtime when loaded
+	contracts   map[string]ContractInfo
+	fetchedAt   time.Time
+}
+
+// NewSymbolMapper reads overrides from SYMBOL_OVERRIDES_FILE (default symbol_overrides.json)
+// and the unconfirmed-project policy from SYMBOL_NAME_CHECK (default warn)
+func NewSymbolMapper() *SymbolMapper {
+	path := os.Getenv("SYMBOL_OVERRIDES_FILE")
+	if path == "" {
+		path = defaultSymbolOverridesFile
+	}
+	nameCheck := strings.ToLower(strings.TrimSpace(os.Getenv("SYMBOL_NAME_CHECK")))
+	if nameCheck != NameCheckStrict {
+		if nameCheck != "" && nameCheck != NameCheckWarn {
+			log.Printf("⚠️ Unknown SYMBOL_NAME_CHECK=%q, using %s", nameCheck, NameCheckWarn)
+		}
+		nameCheck = NameCheckWarn
+	}
+	return &SymbolMapper{overridesFile: path, nameCheck: nameCheck}
+}
+
+// Resolve maps a listing to one Bitget contract, or explains why it can't be traded
+func (sm *SymbolMapper) Resolve(listing ListingInfo) (SymbolMapping, error) {
+	ticker := strings.ToUpper(listing.Symbol)
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sm.reloadOverridesLocked()
+	if mapping, ok, err := sm.resolveOverrideLocked(ticker, listing.Title); ok {
+		return mapping, err
+	}
+
+	if err := sm.refreshContractsLocked(false); err != nil {
+		return SymbolMapping{}, fmt.Errorf("Bitget kontrat listesi alınamadı: %w", err)
+	}
+	candidates := sm.candidatesLocked(ticker)
+	if len(candidates) == 0 && time.Since(sm.fetchedAt) > contractMissRefresh {
+		// Bitget may have opened the contract moments ago
+		if err := sm.refreshContractsLocked(true); err == nil {
+			candidates = sm.candidatesLocked(ticker)
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		return SymbolMapping{}, fmt.Errorf("Bitget'te %s için USDT-M kontrat yok", ticker)
+	case 1:
+		mapping := SymbolMapping{Ticker: ticker, Symbol: candidates[0].Symbol, Source: "contract", Contract: candidates[0]}
+		if candidates[0].Symbol != ticker+"USDT" {
+			mapping.Note = fmt.Sprintf("%s kontratı kullanılıyor", candidates[0].Symbol)
+		}
+		// The title names a project curated under another ticker: a different coin
+		if other := sm.titleContradictionLocked(ticker, listing.Title); other != "" {
+			return SymbolMapping{}, fmt.Errorf("%s: başlıktaki proje adı symbol_overrides.json'da %s için kayıtlı, farklı bir proje olabilir", ticker, other)
+		}
+		// Bitget publishes no project names, so only a curated rule can confirm the ticker
+		// on Bitget is the project Upbit is listing
+		mapping.Verified = sm.titleConfirmedLocked(ticker, listing.Title)
+		if !mapping.Verified {
+			if sm.nameCheck == NameCheckStrict {
+				return SymbolMapping{}, fmt.Errorf("%s: Bitget %s kontratının duyurudaki proje olduğu doğrulanamadı (symbol_overrides.json'a proje adını ekleyin)", ticker, candidates[0].Symbol)
+			}
+			mapping.Note = strings.TrimPrefix(mapping.Note+"; proje adı doğrulanmadı", "; ")
+		}
+		return mapping, nil
+	}
+	symbols := make([]string, len(candidates))
+	for i, contract := range candidates {
+		symbols[i] = contract.Symbol
+	}
+	return SymbolMapping{}, fmt.Errorf("%s belirsiz: birden fazla kontrat eşleşiyor (%s)", ticker, strings.Join(symbols, ", "))
+}
+
+// Contract returns the cached contract config for a Bitget symbol, fetching the list if needed
+func (sm *SymbolMapper) Contract(symbol string) (ContractInfo, bool) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if err := sm.refreshContractsLocked(false); err != nil {
+		return ContractInfo{}, false
+	}
+	contract, ok := sm.contracts[strings.ToUpper(symbol)]
+	return contract, ok
+}
+
+// resolveOverrideLocked applies curated rules; ok=false means no rule covers the ticker
+func (sm *SymbolMapper) resolveOverrideLocked(ticker, title string) (SymbolMapping, bool, error) {
+	var named, fallback []SymbolOverride
+	var expectedNames []string
+	for _, override := range sm.overrides {
+		if !strings.EqualFold(override.Ticker, ticker) {
+			continue
+		}
+		if len(override.Names) == 0 {
+			fallback = append(fallback, override)
+			continue
+		}
+		expectedNames = append(expectedNames, override.Names...)
+		if titleMentions(title, override.Names) {
+			named = append(named, override)
+		}
+	}
+
+	rules := named
+	if len(rules) == 0 {
+		rules = fallback
+	}
+	switch {
+	case len(rules) == 0 && len(expectedNames) > 0:
+		// Known collision: only the listed projects may be traded under this ticker
+		return SymbolMapping{}, true, fmt.Errorf("%s başlıktaki proje adı beklenenlerle eşleşmiyor (%s), farklı bir proje olabilir", ticker, strings.Join(expectedNames, ", "))
+	case len(rules) == 0:
+		return SymbolMapping{}, false, nil
+	case len(rules) > 1:
+		return SymbolMapping{}, true, fmt.Errorf("%s belirsiz: %d özel eşleme kuralı uyuyor", ticker, len(rules))
+	}
+
+	rule := rules[0]
+	if rule.Block {
+		reason := rule.Note
+		if reason == "" {
+			reason = "özel listede engelli"
+		}
+		return SymbolMapping{}, true, fmt.Errorf("%s işlem dışı: %s", ticker, reason)
+	}
+	if rule.Symbol == "" {
+		return SymbolMapping{}, false, nil // Name check only; use the contract list
+	}
+
+	mapping := SymbolMapping{Ticker: ticker, Symbol: strings.ToUpper(rule.Symbol), Source: "override", Note: rule.Note, Verified: true}
+	if err := sm.refreshContractsLocked(false); err == nil {
+		if contract, ok := sm.contracts[mapping.Symbol]; ok {
+			mapping.Contract = contract
+		} else {
+			return SymbolMapping{}, true, fmt.Errorf("%s için tanımlı %s kontratı Bitget'te yok", ticker, mapping.Symbol)
+		}
+	}
+	return mapping, true, nil
+}
+
+// titleConfirmedLocked reports whether a curated rule with names matches the notice title
+func (sm *SymbolMapper) titleConfirmedLocked(ticker, title string) bool {
+	for _, override := range sm.overrides {
+		if strings.EqualFold(override.Ticker, ticker) && !override.Block &&
+			len(override.Names) > 0 && titleMentions(title, override.Names) {
+			return true
+		}
+	}
+	return false
+}
+
+// titleContradictionLocked returns the ticker whose curated project name the title gives for
+// ticker ("Foo Network(BAR)" with Foo Network curated as FOO), "" when nothing contradicts it
+func (sm *SymbolMapper) titleContradictionLocked(ticker, title string) string {
+	lowered := strings.ToLower(title)
+	tag := "(" + strings.ToLower(ticker) + ")"
+	for _, override := range sm.overrides {
+		if strings.EqualFold(override.Ticker, ticker) {
+			continue
+		}
+		for _, name := range override.Names {
+			name = strings.ToLower(name)
+			if strings.Contains(lowered, name+tag) || strings.Contains(lowered, name+" "+tag) {
+				return strings.ToUpper(override.Ticker)
+			}
+		}
+	}
+	return ""
+}
+
+// candidatesLocked returns live contracts for the ticker, preferring the plain TICKERUSDT.
+// Contracts whose base coin is a different asset are never candidates.
+func (sm *SymbolMapper) candidatesLocked(ticker string) []ContractInfo {
+	if contract, ok := sm.contracts[ticker+"USDT"]; ok && contractTradable(contract) && baseCoinMatches(contract, ticker, "") {
+		return []ContractInfo{contract}
+	}
+	var candidates []ContractInfo
+	for _, multiplier := range contractMultipliers {
+		if contract, ok := sm.contracts[multiplier+ticker+"USDT"]; ok && contractTradable(contract) && baseCoinMatches(contract, ticker, multiplier) {
+			candidates = append(candidates, contract)
+		}
+	}
+	return candidates
+}
+
+// baseCoinMatches checks the contract's base coin is the ticker (or its denomination, 1000PEPE)
+func baseCoinMatches(contract ContractInfo, ticker, multiplier string) bool {
+	if contract.BaseCoin == "" {
+		return true // Older responses omit it; the symbol already matched
+	}
+	return strings.EqualFold(contract.BaseCoin, ticker) || strings.EqualFold(contract.BaseCoin, multiplier+ticker)
+}
+
+// contractTradable reports whether Bitget accepts orders on the contract
+func contractTradable(contract ContractInfo) bool {
+	return contract.SymbolStatus == "" || contract.SymbolStatus == "normal"
+}
+
+func (sm *SymbolMapper) refreshContractsLocked(force bool) error {
+	if !force && sm.contracts != nil && time.Since(sm.fetchedAt) < contractCacheTTL {
+		return nil
+	}
+
+	contracts, err := bitgetPublicClient(contractFetchTimeout).GetContracts("")
+	if err != nil {
+		return err
+	}
+
+	sm.contracts = make(map[string]ContractInfo, len(contracts))
+	for _, contract := range contracts {
+		sm.contracts[strings.ToUpper(contract.Symbol)] = contract
+	}
+	sm.fetchedAt = time.Now()
+	return nil
+}
+
+// reloadOverridesLocked re-reads the override file when it changed (missing file = no overrides)
+func (sm *SymbolMapper) reloadOverridesLocked() {
+	info, err := os.Stat(sm.overridesFile)
+	if err != nil {
+		sm.overrides = nil
+		return
+	}
+	if info.ModTime().Equal(sm.overridesAt) {
+		return
+	}
+
+	data, err := ioutil.ReadFile(sm.overridesFile)
+	if err != nil {
+		log.Printf("⚠️ Could not read %s: %v", sm.overridesFile, err)
+		return
+	}
+	var overrides []SymbolOverride
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		log.Printf("⚠️ Could not parse %s: %v", sm.overridesFile, err)
+		return
+	}
+	sm.overrides = overrides
+	sm.overridesAt = info.ModTime()
+	log.Printf("🗺️ Loaded %d symbol overrides from %s", len(overrides), sm.overridesFile)
+}
+
+// titleMentions reports whether the notice title names any of the projects (case-insensitive)
+func titleMentions(title string, names []string) bool {
+	lowered := make([]string, len(names))
+	for i, name := range names {
+		lowered[i] = strings.ToLower(name)
+	}
+	return containsAny(strings.ToLower(title), lowered)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	json "github.com/json-iterator/go"
+)
+
+func newTestSymbolMapper(t *testing.T, nameCheck string, overrides []SymbolOverride, contracts ...ContractInfo) *SymbolMapper {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "symbol_overrides.json")
+	if overrides != nil {
+		data, err := json.Marshal(overrides)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sm := &SymbolMapper{overridesFile: file, nameCheck: nameCheck, contracts: make(map[string]ContractInfo), fetchedAt: time.Now()}
+	for _, contract := range contracts {
+		sm.contracts[contract.Symbol] = contract
+	}
+	return sm
+}
+
+func TestSymbolMapperResolve(t *testing.T) {
+	contracts := []ContractInfo{
+		{Symbol: "FOOUSDT", BaseCoin: "FOO", SymbolStatus: "normal"},
+		{Symbol: "1000PEPEUSDT", BaseCoin: "1000PEPE", SymbolStatus: "normal"},
+		{Symbol: "BARUSDT", BaseCoin: "BARX", SymbolStatus: "normal"},
+		{Symbol: "HALTUSDT", BaseCoin: "HALT", SymbolStatus: "maintain"},
+	}
+	overrides := []SymbolOverride{
+		{Ticker: "FOO", Names: []string{"Foo Network", "푸네트워크"}},
+		{Ticker: "PEPE", Symbol: "1000PEPEUSDT"},
+	}
+
+	tests := []struct {
+		name      string
+		nameCheck string
+		ticker    string
+		title     string
+		want      string // Symbol, "" when refused
+		verified  bool
+		errHas    string
+	}{
+		{"names confirm the title", NameCheckStrict, "FOO", "푸네트워크(FOO) KRW 마켓 디지털 자산 추가", "FOOUSDT", true, ""},
+		{"names do not match the title", NameCheckStrict, "FOO", "다른코인(FOO) KRW 마켓 디지털 자산 추가", "", false, "eşleşmiyor"},
+		{"curated symbol is confirmed", NameCheckStrict, "PEPE", "페페(PEPE) KRW 마켓 디지털 자산 추가", "1000PEPEUSDT", true, ""},
+		{"no rule refuses when strict", NameCheckStrict, "BAZ", "바즈(BAZ) KRW 마켓 디지털 자산 추가", "", false, "doğrulanamadı"},
+		{"no rule trades unverified when warn", NameCheckWarn, "BAZ", "바즈(BAZ) KRW 마켓 디지털 자산 추가", "BAZUSDT", false, ""},
+		{"title naming another curated project refuses", NameCheckWarn, "BAZ", "Foo Network(BAZ) KRW 마켓 디지털 자산 추가", "", false, "farklı bir proje"},
+		{"another project's name elsewhere in the title is no contradiction", NameCheckWarn, "BAZ", "바즈(BAZ), 푸네트워크 관련 안내", "BAZUSDT", false, ""},
+		{"other base coin is never a candidate", NameCheckWarn, "BAR", "바(BAR) KRW 마켓 디지털 자산 추가", "", false, "kontrat yok"},
+		{"halted contract is not a candidate", NameCheckWarn, "HALT", "홀트(HALT) KRW 마켓 디지털 자산 추가", "", false, "kontrat yok"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sm := newTestSymbolMapper(t, tt.nameCheck, overrides, append(contracts, ContractInfo{Symbol: "BAZUSDT", BaseCoin: "BAZ"})...)
+			// Keep the cached list fresh so a miss doesn't hit the network
+			sm.fetchedAt = time.Now().Add(contractMissRefresh)
+
+			mapping, err := sm.Resolve(ListingInfo{Symbol: tt.ticker, Title: tt.title})
+			if tt.want == "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errHas) {
+					t.Fatalf("Resolve = %+v, %v; want error containing %q", mapping, err, tt.errHas)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve error: %v", err)
+			}
+			if mapping.Symbol != tt.want || mapping.Verified != tt.verified {
+				t.Errorf("Resolve = %s verified=%v, want %s verified=%v", mapping.Symbol, mapping.Verified, tt.want, tt.verified)
+			}
+		})
+	}
+}
+
+func TestBaseCoinMatches(t *testing.T) {
+	tests := []struct {
+		baseCoin, ticker, multiplier string
+		want                         bool
+	}{
+		{"", "FOO", "", true},
+		{"FOO", "FOO", "", true},
+		{"foo", "FOO", "", true},
+		{"1000PEPE", "PEPE", "1000", true},
+		{"PEPE", "PEPE", "1000", true},
+		{"BARX", "BAR", "", false},
+		{"10000PEPE", "PEPE", "1000", false},
+	}
+	for _, tt := range tests {
+		if got := baseCoinMatches(ContractInfo{BaseCoin: tt.baseCoin}, tt.ticker, tt.multiplier); got != tt.want {
+			t.Errorf("baseCoinMatches(%q, %q, %q) = %v, want %v", tt.baseCoin, tt.ticker, tt.multiplier, got, tt.want)
+		}
+	}
+}
+
+func TestNewSymbolMapperNameCheckDefault(t *testing.T) {
+	tests := []struct{ env, want string }{
+		{"", NameCheckWarn},
+		{"warn", NameCheckWarn},
+		{"STRICT", NameCheckStrict},
+		{"bogus", NameCheckWarn},
+	}
+	for _, tt := range tests {
+		t.Setenv("SYMBOL_NAME_CHECK", tt.env)
+		if got := NewSymbolMapper().nameCheck; got != tt.want {
+			t.Errorf("SYMBOL_NAME_CHECK=%q: nameCheck = %q, want %q", tt.env, got, tt.want)
+		}
+	}
+}
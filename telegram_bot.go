@@ -17,7 +17,6 @@ import (
         "sync"
         "time"
 
-        "github.com/fsnotify/fsnotify"
         tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
@@ -45,7 +44,39 @@ type UserData struct {
         MarginUSDT    float64   `json:"margin_usdt"`
         Leverage      int       `json:"leverage"`
         IsActive      bool      `json:"is_active"`
+        IsPaused      bool      `json:"is_paused"`            // User paused auto-trading from the menu
         State         UserState `json:"current_state"`
+        // Listing filters (applied before auto-trade)
+        AllowSymbols     []string `json:"allow_symbols,omitempty"` // Empty = all symbols
+        DenySymbols      []string `json:"deny_symbols,omitempty"`
+        ListingSource    string   `json:"listing_source,omitempty"` // Empty = all sources
+        KRWOnly          bool     `json:"krw_only"`
+        MaxBitgetAgeDays int      `json:"max_bitget_age_days"` // 0 = disabled
+        // Manual confirm mode (buy/skip keyboard instead of instant trade)
+        ManualConfirm     bool      `json:"manual_confirm"`
+        ConfirmTimeoutSec int       `json:"confirm_timeout_sec"` // 0 = default 60s
+        MarginPresets     []float64 `json:"margin_presets,omitempty"`
+        // Detection-only alerts (no API keys required)
+        AlertSubscribed bool   `json:"alert_subscribed"`
+        MuteStart       string `json:"mute_start,omitempty"` // "HH:MM" in ALERT_TZ
+        MuteEnd         string `json:"mute_end,omitempty"`
+        // Outbound webhook for this user's events (signed with WebhookSecret)
+        WebhookURL    string `json:"webhook_url,omitempty"`
+        WebhookSecret string `json:"webhook_secret,omitempty"`
+        TradePriority int    `json:"trade_priority,omitempty"` // Higher tiers are executed first (admin /priority)
+        // Margin split for notices listing several tickers (see allocation.go)
+        AllocationPolicy  string  `json:"allocation_policy,omitempty"` // split (default), full, first, cap
+        AllocationCapUSDT float64 `json:"allocation_cap_usdt,omitempty"` // Total margin limit for "cap"
+        // Entry order type (see limit_entry.go)
+        EntryMode      string  `json:"entry_mode,omitempty"`       // market (default), ioc, fok
+        MaxSlippagePct float64 `json:"max_slippage_pct,omitempty"` // Limit cap above last price (default 1%)
+        EntryRetries   int     `json:"entry_retries,omitempty"`    // Re-quotes while size is unfilled
+        TWAPSlices     int     `json:"twap_slices,omitempty"`      // Child orders for entry_mode twap (default 5)
+        TWAPWindowSec  int     `json:"twap_window_sec,omitempty"`  // Window for the slices (default 3s)
+        // Reaction to delisting/caution notices (see negative_events.go)
+        NegativeAction  string  `json:"negative_action,omitempty"`   // off (default), close, short
+        ShortMarginUSDT float64 `json:"short_margin_usdt,omitempty"` // 0 = MarginUSDT
+        ShortLeverage   int     `json:"short_leverage,omitempty"`    // 0 = Leverage
         CreatedAt     string    `json:"created_at"`
         UpdatedAt     string    `json:"updated_at"`
 }
@@ -61,6 +92,14 @@ type PositionInfo struct {
         Leverage    int     `json:"leverage"`
         OpenTime    time.Time `json:"open_time"`
         LastReminder time.Time `json:"last_reminder"`
+        NoticeID    int     `json:"notice_id,omitempty"`    // Upbit notice that triggered the trade
+        NoticeTitle string  `json:"notice_title,omitempty"`
+        // Entry fills from Bitget order detail (OpenPrice/Size are the average fill and filled size)
+        QuotePrice    float64   `json:"quote_price,omitempty"` // Last price the order was sized from
+        FeeUSDT       float64   `json:"fee_usdt"`
+        FilledAt      time.Time `json:"filled_at"`
+        FillConfirmed bool      `json:"fill_confirmed"` // False until Bitget reported the fills
+        ChildOrderIDs []string  `json:"child_order_ids,omitempty"` // TWAP slices aggregated into this position
 }
 
 // ActivePositions stores currently tracked positions with thread-safe access
@@ -83,8 +122,14 @@ type TelegramBot struct {
         database     *BotDatabase
         dbFile       string
         encryptionKey []byte
-        lastProcessedSymbol string // Track last processed coin to prevent duplicates
+        dispatcher   *ListingDispatcher // Dedup ledger shared by all listing sources
+        executor     *TradeExecutor     // Bounded, prioritized per-user trade fan-out
         upbitMonitor *UpbitMonitor // Reference to monitor for trade logging
+        pendingApprovals map[string]*PendingApproval // Manual-confirm trades awaiting buy/skip
+        approvalsMu      sync.Mutex
+        alertChatIDs     []int64        // Channels/groups that receive every listing alert
+        alertTimezone    *time.Location // Timezone for subscriber mute windows
+        adminIDs         map[int64]bool // Operators allowed to run admin commands (ADMIN_USER_IDS)
 }
 
 // Generate encryption key from environment (required for persistence)
@@ -178,6 +223,12 @@ func NewTelegramBot(token string) (*TelegramBot, error) {
                 return nil, fmt.Errorf("failed to setup encryption: %v", err)
         }
 
+        // A corrupt ledger stops startup: an empty one could trade recent listings twice
+        dispatcher, err := NewListingDispatcher("listing_ledger.json")
+        if err != nil {
+                return nil, err
+        }
+
         botInstance := &TelegramBot{
                 bot:           bot,
                 dbFile:        "bot_users.json",
@@ -185,18 +236,31 @@ func NewTelegramBot(token string) (*TelegramBot, error) {
                 database: &BotDatabase{
                         Users: make(map[int64]*UserData),
                 },
+                pendingApprovals: make(map[string]*PendingApproval),
+                alertChatIDs:     loadAlertChatIDs(),
+                alertTimezone:    loadAlertTimezone(),
+                adminIDs:         loadAdminIDs(),
+                dispatcher:       dispatcher,
         }
 
+        botInstance.executor = NewTradeExecutor(botInstance.executeAutoTrade, botInstance.tradeJobFailed)
+
         // Load existing user data (will decrypt automatically)
         if err := botInstance.loadDatabase(); err != nil {
                 log.Printf("Warning: Could not load database: %v", err)
         }
+        botInstance.encryptLegacyWebhookSecrets()
 
         // Load saved positions from previous sessions
         loadActivePositions()
+
+        // Deliver bus events to operator and per-user webhooks
+        NewWebhookDispatcher(botInstance.webhookTargetsFor).Start(eventBus)
         
-        // Start file watcher for upbit_new.json
-        go botInstance.startFileWatcher()
+        // Optional external injection: entries other tools append to LISTING_INJECTION_FILE
+        if listingFileInjectionEnabled() {
+                go botInstance.startFileWatcher()
+        }
         
         // Start position reminder system
         go botInstance.startPositionReminders()
@@ -286,6 +350,16 @@ func (tb *TelegramBot) getUser(userID int64) (*UserData, bool) {
                 }
         }
         
+        if encryptedUser.WebhookSecret != "" {
+                decrypted, err := tb.decryptSensitiveData(encryptedUser.WebhookSecret)
+                if err != nil {
+                        log.Printf("Warning: Failed to decrypt webhook secret for user %d: %v", userID, err)
+                        user.WebhookSecret = ""
+                } else {
+                        user.WebhookSecret = decrypted
+                }
+        }
+        
         return &user, true
 }
 
@@ -326,6 +400,14 @@ func (tb *TelegramBot) saveUser(user *UserData) error {
                 }
                 encryptedUser.BitgetPasskey = encrypted
         }
+        
+        if user.WebhookSecret != "" {
+                encrypted, err := tb.encryptSensitiveData(user.WebhookSecret)
+                if err != nil {
+                        return fmt.Errorf("failed to encrypt webhook secret: %v", err)
+                }
+                encryptedUser.WebhookSecret = encrypted
+        }
 
         tb.database.Users[user.UserID] = &encryptedUser
         return tb.saveDatabaseUnsafe() // Use unsafe version since we already have lock
@@ -369,140 +451,48 @@ func (tb *TelegramBot) getAllActiveUsers() []*UserData {
                                 }
                         }
                         
+                        if encryptedUser.WebhookSecret != "" {
+                                if decrypted, err := tb.decryptSensitiveData(encryptedUser.WebhookSecret); err == nil {
+                                        user.WebhookSecret = decrypted
+                                } else {
+                                        log.Printf("Warning: Failed to decrypt webhook secret for user %d: %v", encryptedUser.UserID, err)
+                                        user.WebhookSecret = ""
+                                }
+                        }
+                        
                         activeUsers = append(activeUsers, &user)
                 }
         }
         return activeUsers
 }
 
-// Start file watcher for upbit_new.json to trigger auto-trading
-func (tb *TelegramBot) startFileWatcher() {
-        log.Printf("🔧 Starting file watcher...")
-        
-        watcher, err := fsnotify.NewWatcher()
-        if err != nil {
-                log.Printf("❌ Failed to create file watcher: %v", err)
+// tradeJobFailed reports a trade job that panicked; an order may already be open
+func (tb *TelegramBot) tradeJobFailed(job *TradeJob) {
+        if job.Task != nil {
+                tb.sendMessage(job.User.UserID, fmt.Sprintf("❌ İşlem görevi beklenmedik bir hatayla durdu: %v\nLütfen pozisyonlarınızı kontrol edin.", job.Err))
                 return
         }
-        defer watcher.Close()
-
-        // Watch upbit_new.json file - use absolute path for reliability
-        upbitFile := "upbit_new.json"
-        
-        // Check if file exists first
-        if _, err := os.Stat(upbitFile); os.IsNotExist(err) {
-                log.Printf("❌ File %s does not exist!", upbitFile)
-                return
-        }
-        
-        err = watcher.Add(upbitFile)
-        if err != nil {
-                log.Printf("❌ Failed to watch %s: %v", upbitFile, err)
-                return
-        }
-
-        log.Printf("👁️  Successfully watching %s for new UPBIT listings...", upbitFile)
-
-        // Initialize with current latest symbol to prevent triggering on startup
-        if latestSymbol := tb.getLatestDetectedSymbol(); latestSymbol != "" {
-                tb.lastProcessedSymbol = latestSymbol
-                log.Printf("🔄 Current latest symbol: %s", latestSymbol)
-        }
-
-        log.Printf("🔄 File watcher ready - waiting for events...")
-        
-        for {
-                select {
-                case event, ok := <-watcher.Events:
-                        if !ok {
-                                log.Printf("❌ File watcher events channel closed")
-                                return
-                        }
-                        log.Printf("📝 File event detected: %s (Op: %v)", event.Name, event.Op)
-                        if event.Op&fsnotify.Write == fsnotify.Write || event.Op&fsnotify.Chmod == fsnotify.Chmod {
-                                log.Printf("🚨 FILE CHANGE EVENT - Processing file change: %s", event.Name)
-                                tb.processUpbitFile()
-                        } else {
-                                log.Printf("📋 Event ignored: %v", event.Op)
-                        }
-                case err, ok := <-watcher.Errors:
-                        if !ok {
-                                log.Printf("❌ File watcher error channel closed")
-                                return
-                        }
-                        log.Printf("❌ File watcher error: %v", err)
-                }
-        }
-}
-
-// Get latest detected symbol from upbit_new.json
-func (tb *TelegramBot) getLatestDetectedSymbol() string {
-        data, err := ioutil.ReadFile("upbit_new.json")
-        if err != nil {
-                log.Printf("Warning: Could not read upbit_new.json: %v", err)
-                return ""
-        }
-
-        // Parse JSONL format - read last line for most recent entry
-        lines := strings.Split(strings.TrimSpace(string(data)), "\n")
-        if len(lines) == 0 {
-                return ""
-        }
-
-        // Get the last non-empty line (most recent entry)
-        var lastEntry ListingEntry
-        for i := len(lines) - 1; i >= 0; i-- {
-                line := strings.TrimSpace(lines[i])
-                if line == "" {
-                        continue
-                }
-                
-                if err := json.Unmarshal([]byte(line), &lastEntry); err != nil {
-                        log.Printf("Warning: Could not parse JSON line: %v", err)
-                        continue
-                }
-                
-                return lastEntry.Symbol
-        }
-
-        return ""
+        symbol := job.Listing.TradingSymbol
+        if symbol == "" {
+                symbol = job.Listing.Symbol
+        }
+        tb.sendMessage(job.User.UserID, fmt.Sprintf("❌ Auto-trade FAILED for %s: %v\nLütfen pozisyonlarınızı kontrol edin.%s", symbol, job.Err, formatNoticeDetails(job.Listing)))
+        eventBus.Publish(EventOrderFailed, job.User.UserID, OrderFailedEvent{
+                Symbol:     symbol,
+                MarginUSDT: job.MarginUSDT,
+                Leverage:   job.User.Leverage,
+                Error:      job.Err.Error(),
+        })
 }
 
-// Process upbit_new.json changes and trigger auto-trading
-func (tb *TelegramBot) processUpbitFile() {
-        latestSymbol := tb.getLatestDetectedSymbol()
-        if latestSymbol == "" {
-                return
-        }
+// executeAutoTrade opens one user's position; runs on a TradeExecutor worker
+func (tb *TelegramBot) executeAutoTrade(job *TradeJob) {
+        user, listing := job.User, job.Listing
+        symbol := listing.Symbol
+        log.Printf("🤖 Auto-trading for user %d (%s) on symbol: %s (priority %d, queued %v)",
+                user.UserID, user.Username, symbol, job.Priority, job.StartedAt.Sub(job.EnqueuedAt).Round(time.Millisecond))
 
-        // Check if this is a new symbol we haven't processed yet
-        if latestSymbol == tb.lastProcessedSymbol {
-                log.Printf("🔄 Symbol %s already processed, skipping", latestSymbol)
-                return
-        }
-
-        // Update last processed symbol
-        tb.lastProcessedSymbol = latestSymbol
-        log.Printf("🚨 NEW UPBIT LISTING DETECTED: %s", latestSymbol)
-
-        // Get all active users for auto-trading
-        activeUsers := tb.getAllActiveUsers()
-        if len(activeUsers) == 0 {
-                log.Printf("⚠️  No active users found for auto-trading")
-                return
-        }
-
-        log.Printf("📊 Triggering auto-trading for %d users on symbol: %s", len(activeUsers), latestSymbol)
-
-        // Trigger auto-trading for each active user
-        for _, user := range activeUsers {
-                go tb.executeAutoTrade(user, latestSymbol)
-        }
-}
-
-// Execute automatic trading for a user when new UPBIT listing is detected
-func (tb *TelegramBot) executeAutoTrade(user *UserData, symbol string) {
-        log.Printf("🤖 Auto-trading for user %d (%s) on symbol: %s", user.UserID, user.Username, symbol)
+        margin := job.MarginUSDT
 
         // Validate user has complete setup
         if user.BitgetAPIKey == "" || user.BitgetSecret == "" || user.BitgetPasskey == "" {
@@ -511,75 +501,152 @@ func (tb *TelegramBot) executeAutoTrade(user *UserData, symbol string) {
                 return
         }
 
-        if user.MarginUSDT <= 0 {
-                log.Printf("⚠️  User %d has invalid margin amount: %f", user.UserID, user.MarginUSDT)
+        if margin <= 0 {
+                log.Printf("⚠️  User %d has invalid margin amount: %f", user.UserID, margin)
                 tb.sendMessage(user.UserID, fmt.Sprintf("🚫 Auto-trade failed for %s: Invalid margin amount. Please /setup first.", symbol))
                 return
         }
 
-        // Format symbol for Bitget (add USDT suffix)
-        tradingSymbol := symbol + "USDT"
-        
-        // Initialize Bitget API client
-        bitgetAPI := NewBitgetAPI(user.BitgetAPIKey, user.BitgetSecret, user.BitgetPasskey)
-        
-        // Pre-warm cache with fast timeout (3 seconds max)
-        log.Printf("🔄 Pre-warming balance cache for user %d...", user.UserID)
-        go func() {
-                if err := bitgetAPI.Cache.RefreshBalance(); err != nil {
-                        log.Printf("⚠️ Balance pre-warm failed for user %d: %v (will check during order)", user.UserID, err)
-                }
-        }()
-        
-        // Small delay to let pre-warm complete if fast
-        time.Sleep(200 * time.Millisecond)
+        // Bitget contract resolved by symbolMapper (1000PEPEUSDT, overrides, ...)
+        tradingSymbol := listing.TradingSymbol
+        if tradingSymbol == "" {
+                log.Printf("⚠️  No Bitget contract mapped for %s, skipping auto-trade for user %d", symbol, user.UserID)
+                tb.sendMessage(user.UserID, fmt.Sprintf("🚫 %s için Bitget kontratı belirlenemedi, işlem açılmadı.", symbol))
+                return
+        }
         
-        // Send notification to user
-        tb.sendMessage(user.UserID, fmt.Sprintf("🚀 Auto-trade triggered for %s\nMargin: %.2f USDT\nLeverage: %dx\nOpening long position...", tradingSymbol, user.MarginUSDT, user.Leverage))
+        // Spread, depth and pre-move guard: skip or downsize before touching the account
+        verdict := preTradeCheck(job, tradingSymbol, margin)
+        if verdict.Skip {
+                tb.sendMessage(user.UserID, fmt.Sprintf("⏭️ %s işlemi atlandı (piyasa kontrolü):\n• %s%s",
+                        tradingSymbol, strings.Join(verdict.Reasons, "\n• "), formatNoticeDetails(listing)))
+                return
+        }
+        margin = verdict.MarginUSDT
+
+        // Shared per-key client: warm connections, and its rate budget spaces out calls
+        bitgetAPI := bitgetClientFor(user)
         
         // Record order sent timestamp
         orderSentAt := time.Now()
         
-        // Execute long position
-        result, err := bitgetAPI.OpenLongPosition(tradingSymbol, user.MarginUSDT, user.Leverage)
+        // Execute long position (Telegram messages wait until the order is out)
+        result, err := bitgetAPI.OpenLongPosition(tradingSymbol, margin, user.Leverage, user.entryParams())
         
         // Record order confirmed timestamp
         orderConfirmedAt := time.Now()
+        tb.logTradeTimings(job, result, orderSentAt, orderConfirmedAt, err)
         
+        if len(verdict.Reasons) > 0 {
+                tb.sendMessage(user.UserID, fmt.Sprintf("📉 %s: %s", tradingSymbol, strings.Join(verdict.Reasons, "; ")))
+        }
+
         if err != nil {
                 log.Printf("❌ Auto-trade failed for user %d on %s: %v", user.UserID, tradingSymbol, err)
-                tb.sendMessage(user.UserID, fmt.Sprintf("❌ Auto-trade FAILED for %s: %v", tradingSymbol, err))
+                tb.sendMessage(user.UserID, fmt.Sprintf("❌ Auto-trade FAILED for %s: %v%s", tradingSymbol, err, formatNoticeDetails(listing)))
+                eventBus.Publish(EventOrderFailed, user.UserID, OrderFailedEvent{
+                        Symbol:     tradingSymbol,
+                        MarginUSDT: margin,
+                        Leverage:   user.Leverage,
+                        Error:      err.Error(),
+                })
                 return
         }
 
         log.Printf("✅ Auto-trade SUCCESS for user %d on %s", user.UserID, tradingSymbol)
+        eventBus.Publish(EventOrderPlaced, user.UserID, OrderPlacedEvent{
+                Symbol:     result.Symbol,
+                OrderID:    result.OrderID,
+                Side:       string(OrderSideBuy),
+                Size:       result.Size,
+                Price:      result.OpenPrice,
+                MarginUSDT: result.MarginUSDT,
+                Leverage:   result.Leverage,
+        })
         
-        // Update trade execution log with Bitget timestamps
-        if tb.upbitMonitor != nil {
-                logEntry := tb.upbitMonitor.GetCurrentLogEntry(symbol)
-                if logEntry != nil {
-                        logEntry.UserID = user.UserID
-                        logEntry.BitgetOrderSentAt = orderSentAt.Format("2006-01-02 15:04:05.000000")
-                        logEntry.BitgetOrderConfirmed = orderConfirmedAt.Format("2006-01-02 15:04:05.000000")
-                        
-                        // Calculate latencies
-                        detectedTime, _ := time.Parse("2006-01-02 15:04:05.000000", logEntry.UpbitDetectedAt)
-                        savedTime, _ := time.Parse("2006-01-02 15:04:05.000000", logEntry.SavedToFileAt)
-                        
-                        logEntry.LatencyBreakdown["detection_to_file_ms"] = savedTime.Sub(detectedTime).Milliseconds()
-                        logEntry.LatencyBreakdown["file_to_bitget_ms"] = orderSentAt.Sub(savedTime).Milliseconds()
-                        logEntry.LatencyBreakdown["bitget_response_ms"] = orderConfirmedAt.Sub(orderSentAt).Milliseconds()
-                        logEntry.LatencyBreakdown["total_execution_ms"] = orderConfirmedAt.Sub(detectedTime).Milliseconds()
-                        
-                        // Save log entry
-                        if err := tb.upbitMonitor.appendTradeLog(logEntry); err != nil {
-                                log.Printf("⚠️ Failed to save trade execution log: %v", err)
-                        }
+        // Send enhanced notification with P&L tracking
+        tb.sendPositionNotification(user.UserID, result, listing)
+}
+
+// logTradeTimings appends one user's queue/dispatch/fill timings and fills to the trade execution log
+func (tb *TelegramBot) logTradeTimings(job *TradeJob, result *OrderResponse, orderSentAt, orderConfirmedAt time.Time, tradeErr error) {
+        if tb.upbitMonitor == nil {
+                return
+        }
+
+        // Each user gets their own copy of the detection entry
+        var entry TradeExecutionLog
+        if current := tb.upbitMonitor.GetCurrentLogEntry(job.Listing.Symbol); current != nil {
+                entry = *current
+        } else {
+                entry = TradeExecutionLog{
+                        Ticker:          job.Listing.Symbol,
+                        NoticeID:        job.Listing.NoticeID,
+                        NoticeTitle:     job.Listing.Title,
+                        NoticeURL:       job.Listing.NoticeURL(),
+                        Markets:         job.Listing.Markets,
+                        MatchedRule:     job.Listing.MatchedRule,
+                        WinningProxy:    job.Listing.Proxy,
+                        UpbitDetectedAt: job.Listing.DetectedAt.In(tb.upbitMonitor.kstLocation).Format("2006-01-02 15:04:05.000000 KST"),
                 }
         }
-        
-        // Send enhanced notification with P&L tracking
-        tb.sendPositionNotification(user.UserID, result)
+
+        const stamp = "2006-01-02 15:04:05.000000"
+        entry.UserID = job.User.UserID
+        entry.Priority = job.Priority
+        entry.QueuedAt = job.EnqueuedAt.Format(stamp)
+        entry.DispatchedAt = job.StartedAt.Format(stamp)
+        entry.BitgetOrderSentAt = orderSentAt.Format(stamp)
+        entry.BitgetOrderConfirmed = orderConfirmedAt.Format(stamp)
+        if tradeErr != nil {
+                entry.Error = tradeErr.Error()
+        }
+        if result != nil {
+                entry.TradingSymbol = result.Symbol
+                entry.OrderID = result.OrderID
+                entry.QuotePrice = result.QuotePrice
+                entry.FillPrice = result.OpenPrice
+                entry.FilledSize = result.Size
+                entry.FeeUSDT = result.FeeUSDT
+                entry.FillConfirmed = result.FillConfirmed
+                if !result.FilledAt.IsZero() {
+                        entry.FilledAt = result.FilledAt.Format(stamp)
+                }
+        }
+
+        breakdown := make(map[string]interface{})
+        // Local stamps only: DetectedAt is on Upbit's clock and would add its offset
+        if !job.Listing.ReceivedAt.IsZero() {
+                breakdown["detection_to_queue_ms"] = job.EnqueuedAt.Sub(job.Listing.ReceivedAt).Milliseconds()
+                breakdown["total_execution_ms"] = orderConfirmedAt.Sub(job.Listing.ReceivedAt).Milliseconds()
+        }
+        breakdown["queue_ms"] = job.StartedAt.Sub(job.EnqueuedAt).Milliseconds()
+        breakdown["dispatch_ms"] = orderSentAt.Sub(job.StartedAt).Milliseconds()
+        breakdown["fill_ms"] = orderConfirmedAt.Sub(orderSentAt).Milliseconds()
+        entry.LatencyBreakdown = breakdown
+
+        if err := tb.upbitMonitor.appendTradeLog(&entry); err != nil {
+                log.Printf("⚠️ Failed to save trade execution log: %v", err)
+        }
+}
+
+// formatNoticeDetails renders the triggering Upbit notice for trade messages ("" if unknown)
+func formatNoticeDetails(listing ListingInfo) string {
+        if listing.NoticeID == 0 && listing.Title == "" {
+                return ""
+        }
+
+        details := fmt.Sprintf("\n\n📰 Notice #%d: %s", listing.NoticeID, listing.Title)
+        if len(listing.Markets) > 0 {
+                details += fmt.Sprintf("\n🏦 Markets: %s", strings.Join(listing.Markets, ", "))
+        }
+        if link := listing.NoticeURL(); link != "" {
+                details += "\n🔗 " + link
+        }
+        if listing.Proxy != "" {
+                details += fmt.Sprintf("\n🛰️ Detected by %s (rule: %s)", listing.Proxy, listing.MatchedRule)
+        }
+        return details
 }
 
 // Send message to user (helper method)
@@ -602,8 +669,14 @@ func (tb *TelegramBot) createMainMenu() tgbotapi.InlineKeyboardMarkup {
                         tgbotapi.NewInlineKeyboardButtonData("🔧 Setup", "setup"),
                         tgbotapi.NewInlineKeyboardButtonData("❌ Pozisyonları Kapat", "close_all"),
                 ),
+                tgbotapi.NewInlineKeyboardRow(
+                        tgbotapi.NewInlineKeyboardButtonData("⏸️ Duraklat", "pause_trading"),
+                        tgbotapi.NewInlineKeyboardButtonData("▶️ Devam Et", "resume_trading"),
+                        tgbotapi.NewInlineKeyboardButtonData("🎛️ Filtreler", "filters"),
+                ),
                 tgbotapi.NewInlineKeyboardRow(
                         tgbotapi.NewInlineKeyboardButtonData("📈 Pozisyonlar", "positions"),
+                        tgbotapi.NewInlineKeyboardButtonData("🔔 Listeleme Alarmı", "alerts"),
                         tgbotapi.NewInlineKeyboardButtonData("❓ Yardım", "help"),
                 ),
         )
@@ -785,6 +858,7 @@ func (tb *TelegramBot) handleSettings(chatID int64, userID int64) {
 • UPBIT Monitoring: Aktif
 • Otomatik İşlem: %s
 • Pozisyon Yönetimi: Otomatik
+• Filtreler: 🎛️ Filtreler butonu
 
 💡 HIZLI İŞLEMLER:
 🔧 Setup değiştir: /setup
@@ -797,7 +871,7 @@ func (tb *TelegramBot) handleSettings(chatID int64, userID int64) {
                 user.Leverage,
                 riskLevel,
                 keyPreview,
-                map[bool]string{true: "🟢 Aktif", false: "🔴 Pasif"}[user.IsActive])
+                autoTradeStatus(user))
 
         log.Printf("📤 Creating plain text settings message for chat %d", chatID)
         msg := tgbotapi.NewMessage(chatID, settingsMsg)
@@ -816,6 +890,17 @@ func (tb *TelegramBot) handleSettings(chatID int64, userID int64) {
         }
 }
 
+// autoTradeStatus describes whether auto-trading will fire for the user
+func autoTradeStatus(user *UserData) string {
+        if !user.IsActive {
+                return "🔴 Pasif"
+        }
+        if user.IsPaused {
+                return "⏸️ Duraklatıldı"
+        }
+        return "🟢 Aktif"
+}
+
 // Handle /close command
 func (tb *TelegramBot) handleClose(chatID int64, userID int64) {
         user, exists := tb.getUser(userID)
@@ -840,7 +925,7 @@ func (tb *TelegramBot) handleClose(chatID int64, userID int64) {
 
 // Close all positions for a user
 func (tb *TelegramBot) closeUserPositions(chatID int64, user *UserData) {
-        api := NewBitgetAPI(user.BitgetAPIKey, user.BitgetSecret, user.BitgetPasskey)
+        api := bitgetClientFor(user)
         
         // Close all USDT futures positions
         resp, err := api.CloseAllPositions()
@@ -853,10 +938,15 @@ func (tb *TelegramBot) closeUserPositions(chatID int64, user *UserData) {
 
         // Clear all positions from tracking (thread-safe)
         positionsMutex.Lock()
-        for positionKey := range activePositions {
+        for positionKey, position := range activePositions {
                 if strings.HasPrefix(positionKey, fmt.Sprintf("%d_", chatID)) {
                         delete(activePositions, positionKey)
                         log.Printf("🗑️ Removed position %s from tracking", positionKey)
+                        eventBus.Publish(EventPositionClosed, user.UserID, PositionClosedEvent{
+                                Symbol:  position.Symbol,
+                                OrderID: resp.OrderID,
+                                Reason:  "close_all",
+                        })
                 }
         }
         positionsMutex.Unlock()
@@ -902,6 +992,36 @@ func (tb *TelegramBot) handleMessage(update tgbotapi.Update) {
                         tb.handleSettings(chatID, userID)
                 case "close":
                         tb.handleClose(chatID, userID)
+                case "pause":
+                        tb.handlePauseToggle(chatID, userID, true)
+                case "resume":
+                        tb.handlePauseToggle(chatID, userID, false)
+                case "filters":
+                        tb.handleFiltersQuery(chatID, userID)
+                case "allow", "deny", "source", "krwonly", "maxage", "allocation", "entry", "negative":
+                        tb.handleFilterCommand(chatID, userID, update.Message.Command(), update.Message.CommandArguments())
+                case "confirm", "confirmtimeout", "presets":
+                        tb.handleConfirmCommand(chatID, userID, update.Message.Command(), update.Message.CommandArguments())
+                case "alerts":
+                        tb.handleAlertsQuery(chatID, userID)
+                case "subscribe":
+                        tb.handleAlertSubscription(chatID, userID, username, true)
+                case "unsubscribe":
+                        tb.handleAlertSubscription(chatID, userID, username, false)
+                case "mute":
+                        tb.handleMuteCommand(chatID, userID, update.Message.CommandArguments())
+                case "webhook":
+                        tb.handleWebhookCommand(chatID, userID, update.Message.CommandArguments())
+                case "rate":
+                        tb.handleRateCommand(chatID, userID)
+                case "reloadproxies":
+                        tb.handleReloadProxiesCommand(chatID, userID)
+                case "proxyreport":
+                        tb.handleProxyReportCommand(chatID, userID, update.Message.CommandArguments())
+                case "health":
+                        tb.handleHealthCommand(chatID, userID)
+                case "priority":
+                        tb.handlePriorityCommand(chatID, userID, update.Message.CommandArguments())
                 case "status":
                         msg := tgbotapi.NewMessage(chatID, "🤖 Bot aktif olarak çalışıyor!")
                         tb.bot.Send(msg)
@@ -997,7 +1117,7 @@ func (tb *TelegramBot) testUserAPI(chatID int64, user *UserData) {
         msg := tgbotapi.NewMessage(chatID, "🔍 API bağlantısı test ediliyor...")
         tb.bot.Send(msg)
 
-        api := NewBitgetAPI(user.BitgetAPIKey, user.BitgetSecret, user.BitgetPasskey)
+        api := bitgetClientFor(user)
         
         // Test API with account balance
         _, err := api.GetAccountBalance()
@@ -1114,6 +1234,18 @@ API bilgilerinizi Bitget > API Management bölümünden alabilirsiniz.
                 }
         case "close_all":
                 tb.handleClose(chatID, userID)
+        case "pause_trading":
+                tb.handlePauseToggle(chatID, userID, true)
+        case "resume_trading":
+                tb.handlePauseToggle(chatID, userID, false)
+        case "filters":
+                tb.handleFiltersQuery(chatID, userID)
+        case "alerts":
+                tb.handleAlertsQuery(chatID, userID)
+        case "alerts_on":
+                tb.handleAlertSubscription(chatID, userID, callback.From.UserName, true)
+        case "alerts_off":
+                tb.handleAlertSubscription(chatID, userID, callback.From.UserName, false)
         case "positions":
                 tb.handlePositionsQuery(chatID, userID)
         case "help":
@@ -1124,6 +1256,8 @@ API bilgilerinizi Bitget > API Management bölümünden alabilirsiniz.
                 if strings.HasPrefix(data, "close_position_") {
                         symbol := strings.TrimPrefix(data, "close_position_")
                         tb.handleCloseSpecificPosition(chatID, userID, symbol)
+                } else if strings.HasPrefix(data, "approve_") || strings.HasPrefix(data, "skip_") {
+                        tb.handleApprovalCallback(chatID, userID, data)
                 }
         }
 }
@@ -1144,7 +1278,7 @@ func (tb *TelegramBot) handleBalanceQuery(chatID int64, userID int64) {
         tb.sendMessage(chatID, "💰 Bakiye bilgileri alınıyor...")
 
         // Get balance using Bitget API
-        api := NewBitgetAPI(user.BitgetAPIKey, user.BitgetSecret, user.BitgetPasskey)
+        api := bitgetClientFor(user)
         balances, err := api.GetAccountBalance()
         if err != nil {
                 tb.sendMessage(chatID, fmt.Sprintf("❌ Bakiye alınamadı: %v", err))
@@ -1189,7 +1323,7 @@ func (tb *TelegramBot) handlePositionsQuery(chatID int64, userID int64) {
         tb.sendMessage(chatID, "📈 Pozisyon bilgileri alınıyor...")
 
         // Get positions using Bitget API
-        api := NewBitgetAPI(user.BitgetAPIKey, user.BitgetSecret, user.BitgetPasskey)
+        api := bitgetClientFor(user)
         positions, err := api.GetAllPositions()
         if err != nil {
                 tb.sendMessage(chatID, fmt.Sprintf("❌ Pozisyonlar alınamadı: %v", err))
@@ -1267,7 +1401,7 @@ func (tb *TelegramBot) handleCloseSpecificPosition(chatID int64, userID int64, s
 
         tb.sendMessage(chatID, fmt.Sprintf("🚨 %s pozisyonu kapatılıyor...", symbol))
 
-        api := NewBitgetAPI(user.BitgetAPIKey, user.BitgetSecret, user.BitgetPasskey)
+        api := bitgetClientFor(user)
         result, err := api.FlashClosePosition(symbol, "long")
         if err != nil {
                 tb.sendMessage(chatID, fmt.Sprintf("❌ %s pozisyonu kapatılamadı: %v", symbol, err))
@@ -1282,6 +1416,12 @@ func (tb *TelegramBot) handleCloseSpecificPosition(chatID int64, userID int64, s
                 log.Printf("🗑️ Removed position %s from tracking", positionKey)
         }
         positionsMutex.Unlock()
+
+        eventBus.Publish(EventPositionClosed, userID, PositionClosedEvent{
+                Symbol:  symbol,
+                OrderID: result.OrderID,
+                Reason:  "manual",
+        })
         
         // Save updated positions to file
         go saveActivePositions()
@@ -1290,32 +1430,29 @@ func (tb *TelegramBot) handleCloseSpecificPosition(chatID int64, userID int64, s
 }
 
 // Send enhanced position notification with P&L tracking
-func (tb *TelegramBot) sendPositionNotification(chatID int64, orderResp *OrderResponse) {
+func (tb *TelegramBot) sendPositionNotification(chatID int64, orderResp *OrderResponse, listing ListingInfo) {
         // Calculate current P&L
         user, exists := tb.getUser(chatID)
         if !exists {
                 return
         }
         
-        api := NewBitgetAPI(user.BitgetAPIKey, user.BitgetSecret, user.BitgetPasskey)
+        api := bitgetClientFor(user)
         currentPrice, err := api.GetSymbolPrice(orderResp.Symbol)
         if err != nil {
                 currentPrice = orderResp.OpenPrice // Fallback to open price
         }
         
-        // Calculate P&L: (CurrentPrice - OpenPrice) * Size
-        priceChange := currentPrice - orderResp.OpenPrice
-        priceChangePercent := (priceChange / orderResp.OpenPrice) * 100
-        usdPnL := priceChange * orderResp.Size
-        usdPnLWithLeverage := usdPnL * float64(orderResp.Leverage)
+        // P&L from the actual fills: (CurrentPrice - AvgFill) * FilledSize - fees
+        priceChange, priceChangePercent, usdPnL := positionPnL(orderResp.OpenPrice, orderResp.Size, orderResp.FeeUSDT, currentPrice)
         
         // Format P&L colors
         pnlIcon := "🔴"
         pnlColor := "📉"
-        if usdPnLWithLeverage > 0 {
+        if usdPnL > 0 {
                 pnlIcon = "🟢"
                 pnlColor = "📈"
-        } else if usdPnLWithLeverage == 0 {
+        } else if usdPnL == 0 {
                 pnlIcon = "⚪"
                 pnlColor = "➡️"
         }
@@ -1327,25 +1464,27 @@ func (tb *TelegramBot) sendPositionNotification(chatID int64, orderResp *OrderRe
 💰 Güncel Fiyat: $%.4f
 📏 Pozisyon Boyutu: %.8f
 ⚖️ Kaldıraç: %dx
-💵 Marjin: %.2f USDT
+💵 Marjin: %.2f USDT%s
 
 %s Fiyat Değişimi: %+.4f (%.2f%%)
 %s P&L: %+.2f USDT
 
 ⏰ Sonraki hatırlatma: 5 dakika
-Pozisyon ID: %s`, 
+Pozisyon ID: %s%s`, 
                 orderResp.Symbol,
                 orderResp.OpenPrice,
                 currentPrice,
                 orderResp.Size,
                 orderResp.Leverage,
                 orderResp.MarginUSDT,
+                formatEntryExecution(orderResp),
                 pnlColor,
                 priceChange,
                 priceChangePercent,
                 pnlIcon,
-                usdPnLWithLeverage,
-                orderResp.OrderID)
+                usdPnL,
+                orderResp.OrderID,
+                formatNoticeDetails(listing))
 
         // Create close position button
         closeButton := tgbotapi.NewInlineKeyboardMarkup(
@@ -1367,8 +1506,7 @@ Pozisyon ID: %s`,
         
         // Store position for tracking and reminders (thread-safe)
         positionKey := fmt.Sprintf("%d_%s", chatID, orderResp.Symbol)
-        positionsMutex.Lock()
-        activePositions[positionKey] = &PositionInfo{
+        position := &PositionInfo{
                 UserID:      chatID,
                 Symbol:      orderResp.Symbol,
                 OrderID:     orderResp.OrderID,
@@ -1378,11 +1516,27 @@ Pozisyon ID: %s`,
                 Leverage:    orderResp.Leverage,
                 OpenTime:    time.Now(),
                 LastReminder: time.Now(),
+                NoticeID:    listing.NoticeID,
+                NoticeTitle: listing.Title,
+                QuotePrice:    orderResp.QuotePrice,
+                FeeUSDT:       orderResp.FeeUSDT,
+                FilledAt:      orderResp.FilledAt,
+                FillConfirmed: orderResp.FillConfirmed,
+                ChildOrderIDs: orderResp.ChildOrderIDs,
         }
+        positionsMutex.Lock()
+        activePositions[positionKey] = position
         positionsMutex.Unlock()
         
         // Save positions to file
         go saveActivePositions()
+
+        // A market fill that wasn't final at placement is confirmed off the trade worker
+        if !position.FillConfirmed && position.OrderID != "" {
+                if user, exists := tb.getUser(chatID); exists {
+                        go tb.confirmFillInBackground(bitgetClientFor(user), position)
+                }
+        }
         
         log.Printf("📝 Position %s tracked for user %d", positionKey, chatID)
 }
@@ -1436,11 +1590,13 @@ func (tb *TelegramBot) sendPositionReminder(position *PositionInfo) {
                 return
         }
         
-        api := NewBitgetAPI(user.BitgetAPIKey, user.BitgetSecret, user.BitgetPasskey)
+        api := bitgetClientFor(user)
+        if !tb.confirmFill(api, position) {
+                return // The entry never filled
+        }
         
-        // Get REAL position data from Bitget (accurate P&L like position display)
+        // Get REAL position data from Bitget (mark price; Bitget's own P&L is logged for comparison)
         positions, err := api.GetAllPositions()
-        var realPnL float64 = 0
         var currentPrice float64 = position.OpenPrice
         
         if err != nil {
@@ -1480,8 +1636,7 @@ func (tb *TelegramBot) sendPositionReminder(position *PositionInfo) {
                 
                 if foundPosition != nil {
                         if pnlFloat, err := strconv.ParseFloat(foundPosition.UnrealizedPL, 64); err == nil {
-                                realPnL = pnlFloat
-                                log.Printf("🎯 Using REAL P&L for %s (matched %s): %.5f USDT", position.Symbol, foundPosition.Symbol, realPnL)
+                                log.Printf("🎯 Bitget P&L for %s (matched %s): %.5f USDT", position.Symbol, foundPosition.Symbol, pnlFloat)
                         }
                         if priceFloat, err := strconv.ParseFloat(foundPosition.MarkPrice, 64); err == nil {
                                 currentPrice = priceFloat
@@ -1498,6 +1653,11 @@ func (tb *TelegramBot) sendPositionReminder(position *PositionInfo) {
                         
                         // Save updated positions
                         go saveActivePositions()
+
+                        eventBus.Publish(EventPositionClosed, position.UserID, PositionClosedEvent{
+                                Symbol: position.Symbol,
+                                Reason: "closed_on_exchange",
+                        })
                         
                         // Notify user that position was closed and tracking stopped
                         closedMsg := fmt.Sprintf(`✅ Pozisyon Kapandı
@@ -1519,11 +1679,10 @@ Bu pozisyon için hatırlatıcılar otomatik olarak durduruldu ve takip listesin
         // Calculate duration
         duration := time.Since(position.OpenTime)
         
-        // Calculate price change for display
-        priceChange := currentPrice - position.OpenPrice
-        priceChangePercent := (priceChange / position.OpenPrice) * 100
+        // P&L from the recorded fills and fees
+        priceChange, priceChangePercent, realPnL := positionPnL(position.OpenPrice, position.Size, position.FeeUSDT, currentPrice)
         
-        // Format P&L colors and icons (using REAL P&L from exchange)
+        // Format P&L colors and icons
         pnlIcon := "🔴"
         pnlColor := "📉"
         statusEmoji := "⚠️"
@@ -1669,33 +1828,6 @@ func InitializeTelegramBot() *TelegramBot {
         return bot
 }
 
-// ExecuteAutoTradeForAllUsers triggers auto-trading for all active users (INSTANT callback)
-func (tb *TelegramBot) ExecuteAutoTradeForAllUsers(symbol string) {
-        log.Printf("⚡ INSTANT EXECUTION - New listing detected: %s", symbol)
-        
-        // Check for duplicate (prevent double execution)
-        if symbol == tb.lastProcessedSymbol {
-                log.Printf("🔄 Symbol %s already processed via instant callback, skipping", symbol)
-                return
-        }
-        
-        tb.lastProcessedSymbol = symbol
-        
-        // Get all active users
-        activeUsers := tb.getAllActiveUsers()
-        if len(activeUsers) == 0 {
-                log.Printf("⚠️  No active users found for auto-trading")
-                return
-        }
-
-        log.Printf("⚡ FAST TRACK: Executing trades for %d users on %s", len(activeUsers), symbol)
-
-        // Execute trades in parallel for speed
-        for _, user := range activeUsers {
-                go tb.executeAutoTrade(user, symbol)
-        }
-}
-
 // StartTradingBot starts the trading bot (to be called from main.go)
 func StartTradingBot() {
         bot := InitializeTelegramBot()
@@ -21,9 +21,11 @@ func main() {
 
 	// Test callback fonksiyonu
 	detectedCoins := []string{}
-	callbackFunc := func(symbol string) {
-		detectedCoins = append(detectedCoins, symbol)
-		log.Printf("🔥 CALLBACK TRIGGERED: New coin detected: %s", symbol)
+	callbackFunc := func(listings []ListingInfo) {
+		for _, listing := range listings {
+			detectedCoins = append(detectedCoins, listing.Symbol)
+			log.Printf("🔥 CALLBACK TRIGGERED: New coin detected: %s", listing.Symbol)
+		}
 	}
 
 	// Upbit monitor oluştur
@@ -33,7 +35,7 @@ func main() {
 	fmt.Printf("   • Proxy Sayısı: %d\n", len(monitor.proxies))
 	fmt.Printf("   • API URL: %s\n", monitor.apiURL)
 	fmt.Printf("   • JSON Dosyası: %s\n", monitor.jsonFile)
-	fmt.Printf("   • User-Agent Pool: %d adet\n", len(monitor.userAgents))
+	fmt.Printf("   • Client Profile: %d adet\n", len(enabledClientProfiles()))
 	fmt.Println()
 
 	// Proxy testleri
@@ -44,16 +46,9 @@ func main() {
 	successfulProxies := 0
 	failedProxies := 0
 
-	for i, proxyURL := range monitor.proxies {
-		fmt.Printf("\n🔍 Testing Proxy #%d: %s\n", i+1, proxyURL[:30]+"...")
+	for _, proxyConfig := range monitor.Proxies() {
+		fmt.Printf("\n🔍 Testing %s: %s\n", proxyConfig.DisplayName(), proxyConfig.ID())
 		
-		client, err := monitor.createProxyClient(proxyURL)
-		if err != nil {
-			fmt.Printf("   ❌ FAILED: %v\n", err)
-			failedProxies++
-			continue
-		}
-
 		// Basit bağlantı testi
 		req, err := monitor.createTestRequest()
 		if err != nil {
@@ -63,7 +58,7 @@ func main() {
 		}
 
 		start := time.Now()
-		resp, err := client.Do(req)
+		resp, err := monitor.clientPool.Do(proxyConfig.ID(), req)
 		latency := time.Since(start)
 
 		if err != nil {
@@ -123,19 +118,19 @@ func main() {
 	fmt.Println("3️⃣  BOT TESPİT KORUMA SİSTEMİ")
 	fmt.Println("═══════════════════════════════════════════════════════════════")
 	
-	fmt.Printf("🎭 User-Agent Pool:\n")
-	for i, ua := range monitor.userAgents {
-		fmt.Printf("   %d. %s...\n", i+1, ua[:50])
+	fmt.Printf("🎭 Proxy Client Profilleri:\n")
+	for _, s := range monitor.ConnStats() {
+		fmt.Printf("   • %s → %s\n", s.ProxyName, s.Profile)
 	}
 	
 	fmt.Println()
 	fmt.Printf("🛡️  Bot Tespit Koruması:\n")
-	fmt.Printf("   • User-Agent Rotation: ✅ Aktif (%d farklı agent)\n", len(monitor.userAgents))
+	fmt.Printf("   • Client Profiles: ✅ Aktif (%d tarayıcı profili, proxy başına sabit)\n", len(enabledClientProfiles()))
 	fmt.Printf("   • Accept Headers: ✅ Browser-like\n")
 	fmt.Printf("   • Referer & Origin: ✅ upbit.com\n")
 	fmt.Printf("   • Sec-Fetch Headers: ✅ Modern browser\n")
 	fmt.Printf("   • Cookie Jar: ✅ Session persistence\n")
-	fmt.Printf("   • TLS Config: ✅ Browser fingerprint\n")
+	fmt.Printf("   • TLS/HTTP2: ✅ Profil ile uyumlu (make tlsprobe)\n")
 	fmt.Println()
 
 	// Rate limit simülasyonu
@@ -249,17 +244,6 @@ func (um *UpbitMonitor) createTestRequest() (*http.Request, error) {
 		return nil, err
 	}
 
-	// Bot detection bypass headers
-	req.Header.Set("User-Agent", um.getRandomUserAgent())
-	req.Header.Set("Accept", "application/json, text/plain, */*")
-	req.Header.Set("Accept-Language", "ko-KR,ko;q=0.9,en-US;q=0.8,en;q=0.7")
-	req.Header.Set("Accept-Encoding", "gzip, deflate, br")
-	req.Header.Set("Referer", "https://upbit.com/")
-	req.Header.Set("Origin", "https://upbit.com")
-	req.Header.Set("Sec-Fetch-Dest", "empty")
-	req.Header.Set("Sec-Fetch-Mode", "cors")
-	req.Header.Set("Sec-Fetch-Site", "same-site")
-	req.Header.Set("Connection", "keep-alive")
-
+	// Browser headers are added by the proxy client's profile
 	return req, nil
 }
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"upbit-bitget-bot/internal/timesync"
+)
+
+const (
+	defaultTimeSyncInterval = time.Minute
+	defaultDriftAlert       = time.Second
+	timeSyncWindow          = 10 // Samples combined per exchange
+	timeSyncStartupSamples  = 3
+)
+
+// clockSync tracks exchange clocks for the whole process; nil until main starts it
+var clockSync *TimeSyncService
+
+// TimeSyncService samples Upbit and Bitget clocks in the background
+type TimeSyncService struct {
+	upbit    *timesync.Tracker
+	bitget   *timesync.Tracker
+	interval time.Duration
+	alertAt  time.Duration // |offset| that triggers an admin alert
+	notify   func(text string)
+
+	alertMu  sync.Mutex
+	alerting map[string]bool // Tracker name -> currently over the threshold
+}
+
+// NewTimeSyncService reads TIME_SYNC_INTERVAL_SEC and TIME_DRIFT_ALERT_MS; notify receives drift alerts
+func NewTimeSyncService(upbitMonitor *UpbitMonitor, notify func(text string)) *TimeSyncService {
+	bitgetClient := &http.Client{Timeout: 10 * time.Second}
+
+	return &TimeSyncService{
+		upbit: timesync.NewTracker("Upbit", func() (timesync.Sample, error) {
+			sample, err := upbitMonitor.GetServerTime()
+			if err != nil {
+				return timesync.Sample{}, err
+			}
+			return *sample, nil
+		}, timeSyncWindow),
+		bitget: timesync.NewTracker("Bitget", func() (timesync.Sample, error) {
+			return timesync.BitgetSample(bitgetClient, "https://api.bitget.com")
+		}, timeSyncWindow),
+		interval: envDuration("TIME_SYNC_INTERVAL_SEC", time.Second, defaultTimeSyncInterval),
+		alertAt:  envDuration("TIME_DRIFT_ALERT_MS", time.Millisecond, defaultDriftAlert),
+		notify:   notify,
+		alerting: make(map[string]bool),
+	}
+}
+
+// envDuration parses a positive integer env var in the given unit, falling back to def
+func envDuration(name string, unit time.Duration, def time.Duration) time.Duration {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil || value <= 0 {
+		log.Printf("⚠️ Invalid %s '%s', using %v", name, raw, def)
+		return def
+	}
+	return time.Duration(value) * unit
+}
+
+// SyncNow takes a few quick samples per exchange and logs the result (startup check)
+func (s *TimeSyncService) SyncNow() {
+	for _, tracker := range []*timesync.Tracker{s.upbit, s.bitget} {
+		var last timesync.Sample
+		var err error
+		for i := 0; i < timeSyncStartupSamples; i++ {
+			if last, _, err = tracker.Sample(); err != nil {
+				break
+			}
+		}
+		if err != nil {
+			log.Printf("⚠️ %s time sync failed: %v", tracker.Name, err)
+			continue
+		}
+
+		estimate := tracker.Estimate()
+		log.Printf("📡 %s TIME SYNC:", tracker.Name)
+		log.Printf("   • Server Time: %s", last.ServerTime.Format("2006-01-02 15:04:05.000"))
+		log.Printf("   • Local Time:  %s", last.LocalTime.Format("2006-01-02 15:04:05.000"))
+		log.Printf("   • Clock Offset: %v (±%v, %d samples)", estimate.Offset, estimate.Uncertainty, estimate.Samples)
+		log.Printf("   • Network Latency: %v", last.NetworkLatency)
+		s.checkDrift(tracker.Name, estimate)
+	}
+}
+
+// Run samples both exchanges every interval until stop is closed
+func (s *TimeSyncService) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+
+		for _, tracker := range []*timesync.Tracker{s.upbit, s.bitget} {
+			_, estimate, err := tracker.Sample()
+			if err != nil {
+				log.Printf("⚠️ %s time sample failed: %v", tracker.Name, err)
+				continue
+			}
+			s.checkDrift(tracker.Name, estimate)
+		}
+	}
+}
+
+// checkDrift alerts admins once when an offset crosses the threshold and again when it recovers
+func (s *TimeSyncService) checkDrift(name string, estimate timesync.Estimate) {
+	over := estimate.Offset.Abs() > s.alertAt
+
+	s.alertMu.Lock()
+	changed := over != s.alerting[name]
+	s.alerting[name] = over
+	s.alertMu.Unlock()
+
+	if !changed {
+		return
+	}
+	if over {
+		log.Printf("⚠️ WARNING: %s clock offset %v exceeds %v!", name, estimate.Offset, s.alertAt)
+		s.sendAlert(fmt.Sprintf("⏰ SAAT SAPMASI: %s\n\n• Sapma: %v (±%v)\n• Eşik: %v\n\nİstek imzaları ve tespit zamanları bu sapmaya göre düzeltiliyor, ancak sunucu saatini kontrol edin (make synctime).",
+			name, estimate.Offset.Round(time.Millisecond), estimate.Uncertainty.Round(time.Millisecond), s.alertAt))
+		return
+	}
+	log.Printf("   ✅ %s clock offset back within %v (%v)", name, s.alertAt, estimate.Offset)
+	s.sendAlert(fmt.Sprintf("✅ %s saat sapması normale döndü: %v", name, estimate.Offset.Round(time.Millisecond)))
+}
+
+func (s *TimeSyncService) sendAlert(text string) {
+	if s.notify != nil {
+		s.notify(text)
+	}
+}
+
+// Estimates returns the current Upbit and Bitget offsets
+func (s *TimeSyncService) Estimates() (upbit, bitget timesync.Estimate) {
+	return s.upbit.Estimate(), s.bitget.Estimate()
+}
+
+// upbitNow is the local clock corrected to Upbit's clock
+func upbitNow() time.Time {
+	if clockSync == nil {
+		return time.Now()
+	}
+	return clockSync.upbit.Now()
+}
+
+// bitgetNow is the local clock corrected to Bitget's clock, used for ACCESS-TIMESTAMP
+func bitgetNow() time.Time {
+	if clockSync == nil {
+		return time.Now()
+	}
+	return clockSync.bitget.Now()
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// probeHello is what the local probe server saw from one client
+type probeHello struct {
+	hello   *tls.ClientHelloInfo
+	proto   string
+	headers http.Header
+}
+
+// probeServer is a local TLS server that records the last ClientHello, protocol and headers
+type probeServer struct {
+	URL  string
+	Pool *x509.CertPool
+
+	mu     sync.Mutex
+	last   probeHello
+	server *http.Server
+}
+
+// startProbeServer listens on 127.0.0.1 with a throwaway certificate offering h2 and http/1.1
+func startProbeServer() (*probeServer, error) {
+	cert, pool, err := probeCertificate()
+	if err != nil {
+		return nil, fmt.Errorf("probe certificate: %w", err)
+	}
+
+	ps := &probeServer{Pool: pool}
+	ps.server = &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ps.mu.Lock()
+			ps.last.proto = r.Proto
+			ps.last.headers = r.Header.Clone()
+			ps.mu.Unlock()
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"success":true}`))
+		}),
+		TLSConfig: &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			NextProtos:   []string{"h2", "http/1.1"},
+			GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+				ps.mu.Lock()
+				ps.last.hello = hello
+				ps.mu.Unlock()
+				return nil, nil
+			},
+		},
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("probe listener: %w", err)
+	}
+	go ps.server.ServeTLS(listener, "", "")
+	ps.URL = "https://" + listener.Addr().String() + "/api/v1/announcements"
+	return ps, nil
+}
+
+// Probe sends one request with the profile's transport and headers and returns what the server saw
+func (ps *probeServer) Probe(profile ClientProfile) (probeHello, error) {
+	transport := &http.Transport{}
+	profile.ConfigureTransport(transport)
+	transport.TLSClientConfig.RootCAs = ps.Pool
+	defer transport.CloseIdleConnections()
+
+	req, _ := http.NewRequest("GET", ps.URL, nil)
+	profile.ApplyHeaders(req)
+
+	ps.mu.Lock()
+	ps.last = probeHello{}
+	ps.mu.Unlock()
+
+	resp, err := (&http.Client{Transport: transport, Timeout: 5 * time.Second}).Do(req)
+	if err != nil {
+		return probeHello{}, err
+	}
+	resp.Body.Close()
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	return ps.last, nil
+}
+
+func (ps *probeServer) Close() {
+	ps.server.Close()
+}
+
+// runTLSProbe starts a local TLS server that logs every ClientHello and sends one request
+// per client profile to it, so fingerprints can be checked without touching Upbit.
+// Usage: go run . tlsprobe (or make tlsprobe)
+func runTLSProbe() {
+	ps, err := startProbeServer()
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return
+	}
+	defer ps.Close()
+
+	fmt.Printf("🔬 TLS probe server on %s\n\n", strings.TrimSuffix(strings.TrimPrefix(ps.URL, "https://"), "/api/v1/announcements"))
+
+	for _, profile := range enabledClientProfiles() {
+		seen, err := ps.Probe(profile)
+		if err != nil {
+			fmt.Printf("❌ %s: %v\n\n", profile.Name, err)
+			continue
+		}
+		printProbeResult(profile, seen)
+	}
+}
+
+func printProbeResult(profile ClientProfile, seen probeHello) {
+	fmt.Printf("━━━ %s (%s) ━━━\n", profile.Name, profile.Family)
+	fmt.Printf("UA:        %s\n", profile.UserAgent)
+	if hello := seen.hello; hello != nil {
+		var versions, ciphers, curves, schemes []string
+		for _, v := range hello.SupportedVersions {
+			versions = append(versions, tls.VersionName(v))
+		}
+		for _, c := range hello.CipherSuites {
+			if isGREASE(c) {
+				ciphers = append(ciphers, "GREASE")
+				continue
+			}
+			ciphers = append(ciphers, tls.CipherSuiteName(c))
+		}
+		for _, c := range hello.SupportedCurves {
+			if isGREASE(uint16(c)) {
+				curves = append(curves, "GREASE")
+				continue
+			}
+			curves = append(curves, c.String())
+		}
+		for _, s := range hello.SignatureSchemes {
+			schemes = append(schemes, s.String())
+		}
+		fmt.Printf("Versions:  %s\n", strings.Join(versions, ", "))
+		fmt.Printf("Ciphers:   %s\n", strings.Join(ciphers, ", "))
+		fmt.Printf("Curves:    %s\n", strings.Join(curves, ", "))
+		fmt.Printf("SigAlgs:   %s\n", strings.Join(schemes, ", "))
+		fmt.Printf("ALPN:      %s\n", strings.Join(hello.SupportedProtos, ", "))
+	}
+	fmt.Printf("Protocol:  %s\n", seen.proto)
+	orDefault := func(v int) string {
+		if v == 0 {
+			return "default"
+		}
+		return fmt.Sprint(v)
+	}
+	fmt.Printf("H2 config: header table %s, stream window %s, conn window %s, max frame %s\n",
+		orDefault(profile.H2HeaderTableSize), orDefault(profile.H2InitialWindowSize),
+		orDefault(profile.H2ConnectionWindow), orDefault(profile.H2MaxFrameSize))
+
+	names := make([]string, 0, len(seen.headers))
+	for name := range seen.headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Println("Headers:")
+	for _, name := range names {
+		fmt.Printf("   %s: %s\n", name, strings.Join(seen.headers[name], ", "))
+	}
+	fmt.Println()
+}
+
+// probeCertificate creates a throwaway self-signed certificate for 127.0.0.1
+func probeCertificate() (tls.Certificate, *x509.CertPool, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "tlsprobe"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+	parsed, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(parsed)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: parsed}, pool, nil
+}
@@ -1,147 +1,67 @@
 package main
 
 import (
-	json "github.com/json-iterator/go"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
-	"strconv"
 	"time"
+
+	"upbit-bitget-bot/internal/timesync"
 )
 
-type TimeSyncResult struct {
-	ServerTime     time.Time
-	LocalTime      time.Time
-	ClockOffset    time.Duration
-	NetworkLatency time.Duration
-}
+// Samples per exchange; their offset bounds are intersected like the bot's background sync
+const samplesPerExchange = 5
 
 func main() {
 	log.SetFlags(0)
-	
+
 	fmt.Println("⏰ Checking time synchronization with exchanges...")
 	fmt.Println()
 
-	upbitSync, err := getUpbitServerTime()
-	if err != nil {
-		log.Printf("❌ Upbit time sync failed: %v\n", err)
-	} else {
-		fmt.Println("📡 UPBIT TIME SYNC:")
-		fmt.Printf("   • Server Time:     %s\n", upbitSync.ServerTime.Format("2006-01-02 15:04:05.000"))
-		fmt.Printf("   • Local Time:      %s\n", upbitSync.LocalTime.Format("2006-01-02 15:04:05.000"))
-		fmt.Printf("   • Clock Offset:    %v\n", upbitSync.ClockOffset)
-		fmt.Printf("   • Network Latency: %v\n", upbitSync.NetworkLatency)
-		
-		if upbitSync.ClockOffset.Abs() > 1*time.Second {
-			fmt.Println("   ⚠️ WARNING: Clock offset > 1s!")
-		} else {
-			fmt.Println("   ✅ Clock sync OK (offset < 1s)")
-		}
-		fmt.Println()
-	}
+	client := &http.Client{Timeout: 10 * time.Second}
 
-	bitgetSync, err := getBitgetServerTime()
-	if err != nil {
-		log.Printf("❌ Bitget time sync failed: %v\n", err)
-	} else {
-		fmt.Println("📡 BITGET TIME SYNC:")
-		fmt.Printf("   • Server Time:     %s\n", bitgetSync.ServerTime.Format("2006-01-02 15:04:05.000"))
-		fmt.Printf("   • Local Time:      %s\n", bitgetSync.LocalTime.Format("2006-01-02 15:04:05.000"))
-		fmt.Printf("   • Clock Offset:    %v\n", bitgetSync.ClockOffset)
-		fmt.Printf("   • Network Latency: %v\n", bitgetSync.NetworkLatency)
-		
-		if bitgetSync.ClockOffset.Abs() > 1*time.Second {
-			fmt.Println("   ⚠️ WARNING: Clock offset > 1s!")
-		} else {
-			fmt.Println("   ✅ Clock sync OK (offset < 1s)")
+	upbit := timesync.NewTracker("UPBIT", func() (timesync.Sample, error) {
+		req, err := http.NewRequest("GET", "https://api.upbit.com/v1/notices?page=1&per_page=1", nil)
+		if err != nil {
+			return timesync.Sample{}, err
 		}
-	}
-}
+		return timesync.HTTPSample(client, req)
+	}, samplesPerExchange)
 
-func getUpbitServerTime() (*TimeSyncResult, error) {
-	localTimeBefore := time.Now()
-	
-	client := &http.Client{Timeout: 10 * time.Second}
-	req, err := http.NewRequest("GET", "https://api.upbit.com/v1/notices?page=1&per_page=1", nil)
-	if err != nil {
-		return nil, err
-	}
-	
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	
-	localTimeAfter := time.Now()
-	
-	dateHeader := resp.Header.Get("Date")
-	if dateHeader == "" {
-		return nil, fmt.Errorf("no Date header")
-	}
-	
-	serverTime, err := time.Parse(time.RFC1123, dateHeader)
-	if err != nil {
-		return nil, err
+	bitget := timesync.NewTracker("BITGET", func() (timesync.Sample, error) {
+		return timesync.BitgetSample(client, "https://api.bitget.com")
+	}, samplesPerExchange)
+
+	for _, tracker := range []*timesync.Tracker{upbit, bitget} {
+		report(tracker)
 	}
-	
-	roundTripTime := localTimeAfter.Sub(localTimeBefore)
-	networkLatency := roundTripTime / 2
-	adjustedServerTime := serverTime.Add(networkLatency)
-	clockOffset := adjustedServerTime.Sub(localTimeAfter)
-	
-	return &TimeSyncResult{
-		ServerTime:     adjustedServerTime,
-		LocalTime:      localTimeAfter,
-		ClockOffset:    clockOffset,
-		NetworkLatency: networkLatency,
-	}, nil
 }
 
-func getBitgetServerTime() (*TimeSyncResult, error) {
-	localTimeBefore := time.Now()
-	
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Get("https://api.bitget.com/api/v2/public/time")
-	if err != nil {
-		return nil, err
+func report(tracker *timesync.Tracker) {
+	var last timesync.Sample
+	var err error
+	for i := 0; i < samplesPerExchange; i++ {
+		if last, _, err = tracker.Sample(); err != nil {
+			break
+		}
+		time.Sleep(200 * time.Millisecond) // Spread samples across the Date header's 1s resolution
 	}
-	defer resp.Body.Close()
-	
-	localTimeAfter := time.Now()
-	
-	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		log.Printf("❌ %s time sync failed: %v\n", tracker.Name, err)
+		return
 	}
-	
-	var result struct {
-		Code string `json:"code"`
-		Data struct {
-			ServerTime string `json:"serverTime"`
-		} `json:"data"`
-	}
-	
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, err
-	}
-	
-	serverTimeMs, err := strconv.ParseInt(result.Data.ServerTime, 10, 64)
-	if err != nil {
-		return nil, err
+
+	estimate := tracker.Estimate()
+	fmt.Printf("📡 %s TIME SYNC:\n", tracker.Name)
+	fmt.Printf("   • Server Time:     %s\n", last.ServerTime.Format("2006-01-02 15:04:05.000"))
+	fmt.Printf("   • Local Time:      %s\n", last.LocalTime.Format("2006-01-02 15:04:05.000"))
+	fmt.Printf("   • Clock Offset:    %v (±%v, %d samples)\n", estimate.Offset, estimate.Uncertainty, estimate.Samples)
+	fmt.Printf("   • Network Latency: %v\n", last.NetworkLatency)
+
+	if estimate.Offset.Abs() > 1*time.Second {
+		fmt.Println("   ⚠️ WARNING: Clock offset > 1s!")
+	} else {
+		fmt.Println("   ✅ Clock sync OK (offset < 1s)")
 	}
-	
-	serverTime := time.UnixMilli(serverTimeMs)
-	roundTripTime := localTimeAfter.Sub(localTimeBefore)
-	networkLatency := roundTripTime / 2
-	adjustedServerTime := serverTime.Add(networkLatency)
-	clockOffset := adjustedServerTime.Sub(localTimeAfter)
-	
-	return &TimeSyncResult{
-		ServerTime:     adjustedServerTime,
-		LocalTime:      localTimeAfter,
-		ClockOffset:    clockOffset,
-		NetworkLatency: networkLatency,
-	}, nil
+	fmt.Println()
 }
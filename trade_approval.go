@@ -0,0 +1,235 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// defaultConfirmTimeout applies when the user has not set ConfirmTimeoutSec
+const defaultConfirmTimeout = 60 * time.Second
+
+// PendingApproval is a detected listing waiting for the user's buy/skip decision
+type PendingApproval struct {
+	ID        string
+	UserID    int64
+	Listing   ListingInfo
+	Margin    float64 // Planned margin for the default Buy button
+	MessageID int
+	ExpiresAt time.Time
+	timer     *time.Timer
+}
+
+// confirmTimeout returns how long a trade approval stays open for the user
+func (u *UserData) confirmTimeout() time.Duration {
+	if u.ConfirmTimeoutSec > 0 {
+		return time.Duration(u.ConfirmTimeoutSec) * time.Second
+	}
+	return defaultConfirmTimeout
+}
+
+// newApprovalID returns a short random ID that fits comfortably in callback data
+func newApprovalID() string {
+	buf := make([]byte, 4)
+	if _, err := rand.Read(buf); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// requestTradeApproval sends the buy/skip keyboard instead of trading immediately
+func (tb *TelegramBot) requestTradeApproval(user *UserData, listing ListingInfo, margin float64) {
+	timeout := user.confirmTimeout()
+	approval := &PendingApproval{
+		ID:        newApprovalID(),
+		UserID:    user.UserID,
+		Listing:   listing,
+		Margin:    margin,
+		ExpiresAt: time.Now().Add(timeout),
+	}
+
+	rows := [][]tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("✅ Al (%.2f USDT)", margin), fmt.Sprintf("approve_%s_0", approval.ID)),
+			tgbotapi.NewInlineKeyboardButtonData("⏭️ Atla", fmt.Sprintf("skip_%s", approval.ID)),
+		),
+	}
+	if len(user.MarginPresets) > 0 {
+		var presetRow []tgbotapi.InlineKeyboardButton
+		for _, preset := range user.MarginPresets {
+			presetRow = append(presetRow, tgbotapi.NewInlineKeyboardButtonData(
+				fmt.Sprintf("💵 %g USDT", preset),
+				fmt.Sprintf("approve_%s_%g", approval.ID, preset)))
+		}
+		rows = append(rows, presetRow)
+	}
+
+	text := fmt.Sprintf(`🔔 Yeni Listeleme - Onay Bekleniyor
+
+💹 Coin: %s
+📄 Kontrat: %s
+⚖️ Kaldıraç: %dx
+💵 Planlanan Marjin: %.2f USDT
+
+Pozisyon açmak için bir marjin seçin.
+⌛ Süre: %s%s`, listing.Symbol, listing.TradingSymbol, user.Leverage, margin, timeout, formatNoticeDetails(listing))
+
+	msg := tgbotapi.NewMessage(user.UserID, text)
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	sent, err := tb.bot.Send(msg)
+	if err != nil {
+		log.Printf("❌ Failed to send trade approval to %d: %v", user.UserID, err)
+		return
+	}
+	approval.MessageID = sent.MessageID
+
+	tb.approvalsMu.Lock()
+	tb.pendingApprovals[approval.ID] = approval
+	approval.timer = time.AfterFunc(timeout, func() { tb.expireApproval(approval.ID) })
+	tb.approvalsMu.Unlock()
+
+	log.Printf("✋ Trade approval %s pending for user %d on %s (expires in %s)", approval.ID, user.UserID, listing.Symbol, timeout)
+}
+
+// takeApproval removes and returns a pending approval so each one resolves exactly once
+func (tb *TelegramBot) takeApproval(id string) *PendingApproval {
+	tb.approvalsMu.Lock()
+	defer tb.approvalsMu.Unlock()
+
+	approval, exists := tb.pendingApprovals[id]
+	if !exists {
+		return nil
+	}
+	delete(tb.pendingApprovals, id)
+	approval.timer.Stop()
+	return approval
+}
+
+// expireApproval closes an approval nobody answered in time
+func (tb *TelegramBot) expireApproval(id string) {
+	approval := tb.takeApproval(id)
+	if approval == nil {
+		return
+	}
+
+	log.Printf("⌛ Trade approval %s expired for user %d on %s", id, approval.UserID, approval.Listing.Symbol)
+	edit := tgbotapi.NewEditMessageText(approval.UserID, approval.MessageID,
+		fmt.Sprintf("⌛ %s için onay süresi doldu, işlem açılmadı.", approval.Listing.Symbol))
+	tb.bot.Send(edit)
+}
+
+// handleApprovalCallback resolves approve_<id>_<margin> and skip_<id> buttons
+func (tb *TelegramBot) handleApprovalCallback(chatID int64, userID int64, data string) {
+	var id string
+	var margin float64
+	approved := strings.HasPrefix(data, "approve_")
+
+	if approved {
+		parts := strings.SplitN(strings.TrimPrefix(data, "approve_"), "_", 2)
+		if len(parts) != 2 {
+			return
+		}
+		id = parts[0]
+		margin, _ = strconv.ParseFloat(parts[1], 64)
+	} else {
+		id = strings.TrimPrefix(data, "skip_")
+	}
+
+	tb.approvalsMu.Lock()
+	approval, exists := tb.pendingApprovals[id]
+	tb.approvalsMu.Unlock()
+	if !exists {
+		tb.sendMessage(chatID, "⌛ Bu onayın süresi dolmuş veya zaten yanıtlanmış.")
+		return
+	}
+	if approval.UserID != userID {
+		return
+	}
+
+	if approval = tb.takeApproval(id); approval == nil {
+		return
+	}
+	symbol := approval.Listing.Symbol
+
+	if !approved {
+		log.Printf("⏭️ User %d skipped %s (approval %s)", userID, symbol, id)
+		tb.bot.Send(tgbotapi.NewEditMessageText(chatID, approval.MessageID, fmt.Sprintf("⏭️ %s atlandı.", symbol)))
+		return
+	}
+
+	user, exists := tb.getUser(userID)
+	if !exists {
+		return
+	}
+	if margin <= 0 {
+		margin = approval.Margin
+	}
+
+	log.Printf("✅ User %d approved %s with %.2f USDT margin (approval %s)", userID, symbol, margin, id)
+	tb.bot.Send(tgbotapi.NewEditMessageText(chatID, approval.MessageID,
+		fmt.Sprintf("✅ %s onaylandı - %.2f USDT marjin ile pozisyon açılıyor...", symbol, margin)))
+
+	tb.executor.Submit(user, approval.Listing, margin)
+}
+
+// handleConfirmCommand updates manual-confirm settings from /confirm, /confirmtimeout and /presets
+func (tb *TelegramBot) handleConfirmCommand(chatID int64, userID int64, command string, args string) {
+	user, exists := tb.getUser(userID)
+	if !exists {
+		tb.sendMessage(chatID, "❌ Henüz hiç kurulum yapmadınız. 🔧 Setup butonuna tıklayın.")
+		return
+	}
+
+	args = strings.TrimSpace(args)
+
+	switch command {
+	case "confirm":
+		switch strings.ToLower(args) {
+		case "on":
+			user.ManualConfirm = true
+		case "off":
+			user.ManualConfirm = false
+		default:
+			tb.sendMessage(chatID, "❌ Kullanım: /confirm on|off")
+			return
+		}
+	case "confirmtimeout":
+		seconds, err := strconv.Atoi(args)
+		if err != nil || seconds < 5 || seconds > 3600 {
+			tb.sendMessage(chatID, "❌ Geçersiz süre! 5-3600 saniye arası girin (örn: /confirmtimeout 60)")
+			return
+		}
+		user.ConfirmTimeoutSec = seconds
+	case "presets":
+		var presets []float64
+		if args != "" && !strings.EqualFold(args, "off") {
+			for _, part := range strings.FieldsFunc(args, func(r rune) bool { return r == ',' || r == ' ' }) {
+				value, err := strconv.ParseFloat(part, 64)
+				if err != nil || value <= 0 {
+					tb.sendMessage(chatID, "❌ Geçersiz tutar! Kullanım: /presets 50,100,200")
+					return
+				}
+				presets = append(presets, value)
+			}
+		}
+		if len(presets) > 4 {
+			tb.sendMessage(chatID, "❌ En fazla 4 hazır marjin tanımlanabilir")
+			return
+		}
+		user.MarginPresets = presets
+	}
+
+	if err := tb.saveUser(user); err != nil {
+		tb.sendMessage(chatID, fmt.Sprintf("❌ Ayar kaydedilemedi: %v", err))
+		return
+	}
+
+	log.Printf("✋ User %d updated /%s %s", userID, command, args)
+	tb.handleFiltersQuery(chatID, userID)
+}
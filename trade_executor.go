@@ -0,0 +1,299 @@
+package main
+
+import (
+	"container/heap"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTradeConcurrency = 8
+	defaultTWAPConcurrency  = 4
+	defaultBitgetKeyRate    = 5.0 // req/s per API key; a market entry needs ~4 calls
+	defaultBitgetKeyBurst   = 5.0
+	minBitgetKeyRate        = 0.1 // req/s; keeps the refill wait finite
+	maxTradePriority        = 9
+)
+
+// TradeJob is one user's entry on one listing
+type TradeJob struct {
+	User       *UserData
+	Listing    ListingInfo
+	MarginUSDT float64 // From the user's allocation plan
+	Priority   int     // Higher runs first (UserData.TradePriority)
+	EnqueuedAt time.Time
+	StartedAt  time.Time // When a worker picked the job up
+	Task       func()    // Non-entry work (e.g. closing on a delisting); runs instead of the entry when set
+	Err        error     // Set when the job panicked
+
+	seq uint64 // FIFO within a priority tier
+}
+
+// tradeQueue orders jobs by priority, then arrival
+type tradeQueue []*TradeJob
+
+func (q tradeQueue) Len() int { return len(q) }
+func (q tradeQueue) Less(i, j int) bool {
+	if q[i].Priority != q[j].Priority {
+		return q[i].Priority > q[j].Priority
+	}
+	return q[i].seq < q[j].seq
+}
+func (q tradeQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *tradeQueue) Push(x interface{}) { *q = append(*q, x.(*TradeJob)) }
+func (q *tradeQueue) Pop() interface{} {
+	old := *q
+	job := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return job
+}
+
+// tradePool is a fixed number of workers draining one priority queue
+type tradePool struct {
+	exec *TradeExecutor
+
+	mu    sync.Mutex
+	cond  *sync.Cond
+	queue tradeQueue
+	seq   uint64
+}
+
+func newTradePool(exec *TradeExecutor, workers int) *tradePool {
+	p := &tradePool{exec: exec}
+	p.cond = sync.NewCond(&p.mu)
+	for i := 0; i < workers; i++ {
+		go p.worker()
+	}
+	return p
+}
+
+func (p *tradePool) push(job *TradeJob) {
+	p.mu.Lock()
+	p.seq++
+	job.seq = p.seq
+	job.EnqueuedAt = time.Now()
+	heap.Push(&p.queue, job)
+	p.mu.Unlock()
+	p.cond.Signal()
+}
+
+func (p *tradePool) pending() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.queue.Len()
+}
+
+func (p *tradePool) worker() {
+	for {
+		p.mu.Lock()
+		for p.queue.Len() == 0 {
+			p.cond.Wait()
+		}
+		job := heap.Pop(&p.queue).(*TradeJob)
+		p.mu.Unlock()
+
+		p.exec.runJob(job)
+	}
+}
+
+// TradeExecutor runs trade jobs on a fixed number of workers, highest priority first,
+// so a listing with many users doesn't burst Bitget and Telegram all at once. TWAP entries
+// hold their worker for the whole window, so they get a pool of their own and never
+// delay market/limit entries or higher priority tiers.
+type TradeExecutor struct {
+	run    func(job *TradeJob)
+	failed func(job *TradeJob) // Told about a job that panicked (job.Err is set)
+
+	entries *tradePool // Market, IOC/FOK entries and tasks
+	twap    *tradePool // TWAP entries
+}
+
+// NewTradeExecutor reads TRADE_CONCURRENCY and TWAP_CONCURRENCY and starts the workers
+func NewTradeExecutor(run func(job *TradeJob), failed func(job *TradeJob)) *TradeExecutor {
+	workers := max(1, int(envFloat("TRADE_CONCURRENCY", defaultTradeConcurrency)))
+	twapWorkers := max(1, int(envFloat("TWAP_CONCURRENCY", defaultTWAPConcurrency)))
+
+	e := &TradeExecutor{run: run, failed: failed}
+	e.entries = newTradePool(e, workers)
+	e.twap = newTradePool(e, twapWorkers)
+	log.Printf("🧵 Trade executor started with %d workers (+%d for TWAP entries)", workers, twapWorkers)
+	return e
+}
+
+// Submit queues a user's trade with the given margin
+func (e *TradeExecutor) Submit(user *UserData, listing ListingInfo, marginUSDT float64) {
+	pool := e.entries
+	if user.entryParams().Mode == EntryTWAP {
+		pool = e.twap
+	}
+	pool.push(&TradeJob{
+		User:       user,
+		Listing:    listing,
+		MarginUSDT: marginUSDT,
+		Priority:   user.TradePriority,
+	})
+}
+
+// SubmitTask queues other trading work for a user on the entry workers, so it shares the
+// concurrency limit and priority order with entries
+func (e *TradeExecutor) SubmitTask(user *UserData, priority int, task func()) {
+	e.entries.push(&TradeJob{
+		User:     user,
+		Priority: priority,
+		Task:     task,
+	})
+}
+
+// Pending returns the number of queued jobs not yet picked up
+func (e *TradeExecutor) Pending() int {
+	return e.entries.pending() + e.twap.pending()
+}
+
+// runJob runs one job; a panic is logged and reported as a failed job instead of
+// taking the whole bot down
+func (e *TradeExecutor) runJob(job *TradeJob) {
+	defer func() {
+		if r := recover(); r != nil {
+			job.Err = fmt.Errorf("panic: %v", r)
+			log.Printf("❌ Trade job for user %d on %q panicked: %v\n%s", job.User.UserID, job.Listing.Symbol, r, debug.Stack())
+			if e.failed != nil {
+				e.failed(job)
+			}
+		}
+	}()
+
+	job.StartedAt = time.Now()
+	if job.Task != nil {
+		job.Task()
+		return
+	}
+	e.run(job)
+}
+
+// RateBudget is a token bucket shared by all requests signed with one API key
+type RateBudget struct {
+	rate  float64 // Tokens per second
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// Wait blocks until a request fits in the budget; a budget without a positive rate refills at
+// defaultBitgetKeyRate so it can never stall its key
+func (rb *RateBudget) Wait() {
+	rate := rb.rate
+	if rate <= 0 {
+		rate = defaultBitgetKeyRate
+	}
+	for {
+		rb.mu.Lock()
+		now := time.Now()
+		rb.tokens = min(rb.burst, rb.tokens+now.Sub(rb.last).Seconds()*rate)
+		rb.last = now
+		if rb.tokens >= 1 {
+			rb.tokens--
+			rb.mu.Unlock()
+			return
+		}
+		wait := time.Duration((1 - rb.tokens) / rate * float64(time.Second))
+		rb.mu.Unlock()
+		time.Sleep(wait)
+	}
+}
+
+var (
+	bitgetBudgetsMu sync.Mutex
+	bitgetBudgets   = make(map[string]*RateBudget)
+	bitgetClientsMu sync.Mutex
+	bitgetClients   = make(map[string]*BitgetAPI)
+	bitgetPublicMu  sync.Mutex
+	bitgetPublic    = make(map[time.Duration]*BitgetAPI)
+)
+
+// bitgetBudgetFor returns the budget for an API key (BITGET_KEY_RATE / BITGET_KEY_BURST)
+func bitgetBudgetFor(apiKey string) *RateBudget {
+	bitgetBudgetsMu.Lock()
+	defer bitgetBudgetsMu.Unlock()
+
+	if budget, ok := bitgetBudgets[apiKey]; ok {
+		return budget
+	}
+	rate := max(minBitgetKeyRate, envFloat("BITGET_KEY_RATE", defaultBitgetKeyRate))
+	burst := max(1, envFloat("BITGET_KEY_BURST", defaultBitgetKeyBurst))
+	budget := &RateBudget{rate: rate, burst: burst, tokens: burst, last: time.Now()}
+	bitgetBudgets[apiKey] = budget
+	return budget
+}
+
+// bitgetClientFor reuses one client per credential set so trades start on warm connections
+func bitgetClientFor(user *UserData) *BitgetAPI {
+	key := user.BitgetAPIKey + "\x00" + user.BitgetSecret + "\x00" + user.BitgetPasskey
+
+	bitgetClientsMu.Lock()
+	defer bitgetClientsMu.Unlock()
+
+	if api, ok := bitgetClients[key]; ok {
+		return api
+	}
+	api := NewBitgetAPI(user.BitgetAPIKey, user.BitgetSecret, user.BitgetPasskey)
+	bitgetClients[key] = api
+	return api
+}
+
+// bitgetPublicClient reuses one unsigned client per timeout for public market data
+// (contracts, order book, candles), like bitgetClientFor does for signed clients
+func bitgetPublicClient(timeout time.Duration) *BitgetAPI {
+	bitgetPublicMu.Lock()
+	defer bitgetPublicMu.Unlock()
+
+	if api, ok := bitgetPublic[timeout]; ok {
+		return api
+	}
+	api := NewBitgetAPI("", "", "")
+	api.Client.Timeout = timeout
+	bitgetPublic[timeout] = api
+	return api
+}
+
+// handlePriorityCommand sets a user's trade priority tier: /priority <user_id> <0-9>
+func (tb *TelegramBot) handlePriorityCommand(chatID int64, userID int64, args string) {
+	if !tb.requireAdmin(chatID, userID) {
+		return
+	}
+
+	fields := strings.Fields(args)
+	if len(fields) != 2 {
+		tb.sendMessage(chatID, fmt.Sprintf("❌ Kullanım: /priority <kullanıcı_id> <0-%d>\nYüksek öncelikli kullanıcıların işlemleri önce açılır.", maxTradePriority))
+		return
+	}
+	targetID, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		tb.sendMessage(chatID, "❌ Geçersiz kullanıcı ID.")
+		return
+	}
+	priority, err := strconv.Atoi(fields[1])
+	if err != nil || priority < 0 || priority > maxTradePriority {
+		tb.sendMessage(chatID, fmt.Sprintf("❌ Öncelik 0-%d arasında olmalı.", maxTradePriority))
+		return
+	}
+
+	user, exists := tb.getUser(targetID)
+	if !exists {
+		tb.sendMessage(chatID, "❌ Kullanıcı bulunamadı.")
+		return
+	}
+	user.TradePriority = priority
+	if err := tb.saveUser(user); err != nil {
+		tb.sendMessage(chatID, fmt.Sprintf("❌ Ayar kaydedilemedi: %v", err))
+		return
+	}
+	log.Printf("🎚️ Admin %d set trade priority of user %d to %d", userID, targetID, priority)
+	tb.sendMessage(chatID, fmt.Sprintf("✅ Kullanıcı %d işlem önceliği: %d", targetID, priority))
+}
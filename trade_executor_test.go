@@ -0,0 +1,78 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTradeExecutorTWAPDoesNotBlockEntries(t *testing.T) {
+	t.Setenv("TRADE_CONCURRENCY", "1")
+	t.Setenv("TWAP_CONCURRENCY", "1")
+
+	release := make(chan struct{})
+	defer close(release)
+	done := make(chan string, 2)
+	e := NewTradeExecutor(func(job *TradeJob) {
+		if job.User.EntryMode == EntryTWAP {
+			<-release // A TWAP window still running
+		}
+		done <- job.Listing.Symbol
+	}, nil)
+
+	e.Submit(&UserData{UserID: 1, EntryMode: EntryTWAP}, ListingInfo{Symbol: "TWAP"}, 10)
+	e.Submit(&UserData{UserID: 2}, ListingInfo{Symbol: "MARKET"}, 10)
+
+	select {
+	case symbol := <-done:
+		if symbol != "MARKET" {
+			t.Fatalf("first finished job = %s, want MARKET", symbol)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("market entry waited behind a TWAP entry")
+	}
+}
+
+func TestTradeExecutorRecoversPanics(t *testing.T) {
+	t.Setenv("TRADE_CONCURRENCY", "1")
+
+	failed := make(chan *TradeJob, 1)
+	done := make(chan struct{}, 1)
+	e := NewTradeExecutor(func(job *TradeJob) {
+		if job.Listing.Symbol == "BOOM" {
+			panic("nil map")
+		}
+		done <- struct{}{}
+	}, func(job *TradeJob) { failed <- job })
+
+	e.Submit(&UserData{UserID: 1}, ListingInfo{Symbol: "BOOM"}, 10)
+	select {
+	case job := <-failed:
+		if job.Err == nil || job.Listing.Symbol != "BOOM" {
+			t.Errorf("failed job = %+v, want BOOM with an error", job)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("panicking job was not reported")
+	}
+
+	// The single worker survived and keeps taking jobs
+	e.Submit(&UserData{UserID: 2}, ListingInfo{Symbol: "OK"}, 10)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("worker died with the panicking job")
+	}
+}
+
+func TestRateBudgetWithoutRateStillRefills(t *testing.T) {
+	rb := &RateBudget{burst: 1, last: time.Now()} // Zero rate, no tokens left
+	done := make(chan struct{})
+	go func() {
+		rb.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Wait stalled on a zero-rate budget")
+	}
+}
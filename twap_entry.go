@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTWAPSlices = 5
+	defaultTWAPWindow = 3 * time.Second
+	maxTWAPSlices     = 20
+	maxTWAPWindowSec  = 60
+)
+
+// parseTWAPArgs parses "[slices] [seconds] [cap%]" after "/entry twap"
+func parseTWAPArgs(fields []string) (EntryParams, error) {
+	params := EntryParams{Mode: EntryTWAP}
+	if len(fields) > 3 {
+		return EntryParams{}, fmt.Errorf("fazla parametre")
+	}
+	if len(fields) > 0 {
+		slices, err := strconv.Atoi(fields[0])
+		if err != nil || slices < 2 || slices > maxTWAPSlices {
+			return EntryParams{}, fmt.Errorf("parça sayısı 2-%d arasında olmalı", maxTWAPSlices)
+		}
+		params.Slices = slices
+	}
+	if len(fields) > 1 {
+		seconds, err := strconv.Atoi(strings.TrimSuffix(fields[1], "s"))
+		if err != nil || seconds < 1 || seconds > maxTWAPWindowSec {
+			return EntryParams{}, fmt.Errorf("süre 1-%d saniye olmalı", maxTWAPWindowSec)
+		}
+		params.Window = time.Duration(seconds) * time.Second
+	}
+	if len(fields) > 2 {
+		capPct, err := strconv.ParseFloat(strings.TrimSuffix(fields[2], "%"), 64)
+		if err != nil || capPct <= 0 || capPct > 50 {
+			return EntryParams{}, fmt.Errorf("fiyat sınırı 0-50 arasında olmalı")
+		}
+		params.MaxSlippagePct = capPct
+	}
+	return params, nil
+}
+
+// openLongTWAP splits the entry into Slices IOC child orders spread evenly over Window, each
+// limited to the first quote + MaxSlippagePct; it stops early once the price runs past that cap.
+// Fills are aggregated into one response with a volume-weighted entry price.
+func (b *BitgetAPI) openLongTWAP(symbol string, quote, targetSize, marginUSDT float64, leverage int, entry EntryParams) (*OrderResponse, error) {
+	priceDecimals, sizeDecimals, minSize, err := b.contractPrecision(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	capPrice := floorTo(quote*(1+entry.MaxSlippagePct/100), priceDecimals)
+	capStr := strconv.FormatFloat(capPrice, 'f', priceDecimals, 64)
+	interval := time.Duration(0)
+	if entry.Slices > 1 {
+		interval = entry.Window / time.Duration(entry.Slices-1)
+	}
+
+	resp := &OrderResponse{
+		Symbol:        symbol,
+		Leverage:      leverage,
+		EntryMode:     EntryTWAP,
+		QuotePrice:    quote,
+		RequestedSize: targetSize,
+		Slices:        entry.Slices,
+	}
+	filled, cost := 0.0, 0.0
+	start := time.Now()
+
+	for i := 0; i < entry.Slices; i++ {
+		if i > 0 {
+			time.Sleep(time.Until(start.Add(time.Duration(i) * interval)))
+			price, err := b.GetSymbolPrice(symbol)
+			if err != nil {
+				resp.AbortReason = fmt.Sprintf("fiyat alınamadı: %v", err)
+				break
+			}
+			if price > capPrice {
+				resp.AbortReason = fmt.Sprintf("fiyat $%.6g sınırı ($%s) aştı", price, capStr)
+				break
+			}
+		}
+
+		// Unfilled size from earlier slices rolls into the remaining ones
+		remaining := floorTo(targetSize-filled, sizeDecimals)
+		size := floorTo(remaining/float64(entry.Slices-i), sizeDecimals)
+		if size < minSize || size <= 0 {
+			size = remaining
+		}
+		if size < minSize || size <= 0 {
+			break
+		}
+
+		resp.Attempts++
+		order, err := b.PlaceLimitOrder(symbol, OrderSideBuy, strconv.FormatFloat(size, 'f', sizeDecimals, 64), capStr, EntryIOC)
+		if err != nil {
+			resp.AbortReason = fmt.Sprintf("parça %d gönderilemedi: %v", i+1, err)
+			break
+		}
+		// A slice still live is cancelled and re-read before the next one is sized; if it can't be
+		// settled its known fills are kept and the entry stops, so slices never overlap
+		detail, err := b.waitOrderFinal(symbol, order.OrderID)
+		if detail != nil && detail.Filled() > 0 {
+			got := detail.Filled()
+			filled += got
+			cost += got * detail.AvgPrice()
+			resp.FeeUSDT += detail.FeeUSDT()
+			resp.FilledAt = detail.FilledAt()
+			resp.ChildOrderIDs = append(resp.ChildOrderIDs, order.OrderID)
+			resp.OrderID = order.OrderID
+		}
+		if err != nil {
+			resp.AbortReason = fmt.Sprintf("parça %d sonuçlanmadı: %v", i+1, err)
+			break
+		}
+		log.Printf("🧩 %s TWAP slice %d/%d: %.8g/%.8g filled @ ≤ %s", symbol, i+1, entry.Slices, detail.Filled(), size, capStr)
+	}
+
+	if filled == 0 {
+		if resp.AbortReason != "" {
+			return nil, fmt.Errorf("TWAP entry aborted without fills: %s", resp.AbortReason)
+		}
+		return nil, fmt.Errorf("TWAP entry got no fills within %.2f%% of last price", entry.MaxSlippagePct)
+	}
+
+	resp.Size = filled
+	resp.OpenPrice = cost / filled // Volume-weighted over all child fills
+	resp.FillConfirmed = true
+	resp.UnfilledSize = floorTo(max(0, targetSize-filled), sizeDecimals)
+	resp.MarginUSDT = marginUSDT * filled / targetSize
+	if resp.AbortReason != "" {
+		log.Printf("⛔ %s TWAP stopped after %d/%d slices: %s", symbol, resp.Attempts, entry.Slices, resp.AbortReason)
+	}
+	log.Printf("✅ %s TWAP filled %.8g/%.8g in %d child orders at VWAP %.8g (quote %.8g, %v)",
+		symbol, filled, targetSize, len(resp.ChildOrderIDs), resp.OpenPrice, quote, time.Since(start).Round(time.Millisecond))
+	return resp, nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTWAPArgs(t *testing.T) {
+	tests := []struct {
+		args    string
+		want    EntryParams
+		wantErr bool
+	}{
+		{"", EntryParams{Mode: EntryTWAP}, false},
+		{"4", EntryParams{Mode: EntryTWAP, Slices: 4}, false},
+		{"4 10", EntryParams{Mode: EntryTWAP, Slices: 4, Window: 10 * time.Second}, false},
+		{"4 10s 2%", EntryParams{Mode: EntryTWAP, Slices: 4, Window: 10 * time.Second, MaxSlippagePct: 2}, false},
+		{fmt.Sprintf("%d %d 50", maxTWAPSlices, maxTWAPWindowSec), EntryParams{Mode: EntryTWAP, Slices: maxTWAPSlices, Window: maxTWAPWindowSec * time.Second, MaxSlippagePct: 50}, false},
+		{"1", EntryParams{}, true},
+		{fmt.Sprint(maxTWAPSlices + 1), EntryParams{}, true},
+		{"x", EntryParams{}, true},
+		{"4 0", EntryParams{}, true},
+		{fmt.Sprintf("4 %d", maxTWAPWindowSec+1), EntryParams{}, true},
+		{"4 10 0", EntryParams{}, true},
+		{"4 10 51", EntryParams{}, true},
+		{"4 10 1 extra", EntryParams{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseTWAPArgs(strings.Fields(tt.args))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseTWAPArgs(%q) error = %v, wantErr %v", tt.args, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("parseTWAPArgs(%q) = %+v, want %+v", tt.args, got, tt.want)
+		}
+	}
+}
+
+// A slice that can't be cancelled keeps its fills and stops the entry before the next slice
+func TestTWAPAbortsWhenSliceCannotBeCancelled(t *testing.T) {
+	withTestContracts(t, map[string]ContractInfo{"FOOUSDT": {Symbol: "FOOUSDT", PricePlace: "4", VolumePlace: "0", MinTradeNum: "1"}})
+
+	orders := &fakeOrderServer{state: "partially_filled", filled: "3", cancelFail: true}
+	placed := 0
+	api := newFakeBitget(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v2/mix/order/place-order" {
+			placed++
+			fmt.Fprint(w, `{"code":"00000","data":{"orderId":"1"}}`)
+			return
+		}
+		orders.ServeHTTP(w, r)
+	}))
+
+	resp, err := api.openLongTWAP("FOOUSDT", 1.5, 10, 10, 2, EntryParams{Mode: EntryTWAP, Slices: 3, Window: time.Second, MaxSlippagePct: 1})
+	if err != nil {
+		t.Fatalf("openLongTWAP: %v", err)
+	}
+	if placed != 1 {
+		t.Errorf("placed %d slices, want 1", placed)
+	}
+	if resp.Size != 3 || resp.UnfilledSize != 7 || !strings.Contains(resp.AbortReason, "sonuçlanmadı") {
+		t.Errorf("resp size=%v unfilled=%v abort=%q, want 3 filled, 7 unfilled, aborted", resp.Size, resp.UnfilledSize, resp.AbortReason)
+	}
+}
@@ -0,0 +1,406 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// listingSkipReason returns why a listing should not be traded for this user ("" = trade it).
+// bitgetLaunch is the Bitget contract launch time, zero when unknown or not listed.
+func (u *UserData) listingSkipReason(listing ListingInfo, bitgetLaunch time.Time) string {
+	symbol := strings.ToUpper(listing.Symbol)
+
+	if len(u.AllowSymbols) > 0 && !containsSymbol(u.AllowSymbols, symbol) {
+		return fmt.Sprintf("%s izin listesinde değil", symbol)
+	}
+
+	if containsSymbol(u.DenySymbols, symbol) {
+		return fmt.Sprintf("%s engel listesinde", symbol)
+	}
+
+	if u.ListingSource != "" && u.ListingSource != string(listing.Source) {
+		return fmt.Sprintf("kaynak %s, sadece %s kabul ediliyor", listing.Source, u.ListingSource)
+	}
+
+	if u.KRWOnly && !containsSymbol(listing.Markets, "KRW") {
+		return "KRW marketi listelemesi değil"
+	}
+
+	if u.MaxBitgetAgeDays > 0 && !bitgetLaunch.IsZero() {
+		age := time.Since(bitgetLaunch)
+		if age > time.Duration(u.MaxBitgetAgeDays)*24*time.Hour {
+			return fmt.Sprintf("Bitget'te %.0f gündür listeli (limit: %d gün)", age.Hours()/24, u.MaxBitgetAgeDays)
+		}
+	}
+
+	return ""
+}
+
+// containsSymbol reports whether list contains symbol (case-insensitive)
+func containsSymbol(list []string, symbol string) bool {
+	for _, s := range list {
+		if strings.EqualFold(s, symbol) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatMarginPresets renders margin presets for the filters screen
+func formatMarginPresets(presets []float64) string {
+	if len(presets) == 0 {
+		return "yok"
+	}
+	parts := make([]string, 0, len(presets))
+	for _, preset := range presets {
+		parts = append(parts, fmt.Sprintf("%g USDT", preset))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// parseSymbolList parses "ABC, DEF XYZ" into upper-case symbols; "-" or "off" clears the list
+func parseSymbolList(args string) []string {
+	args = strings.TrimSpace(args)
+	if args == "" || args == "-" || strings.EqualFold(args, "off") {
+		return nil
+	}
+
+	var symbols []string
+	for _, part := range strings.FieldsFunc(args, func(r rune) bool { return r == ',' || r == ' ' }) {
+		symbol := strings.ToUpper(strings.TrimSpace(part))
+		if symbol != "" && !containsSymbol(symbols, symbol) {
+			symbols = append(symbols, symbol)
+		}
+	}
+	return symbols
+}
+
+// tradeForEligibleUsers applies pause state, per-user filters and allocation policy to the new
+// listings of one notice, then starts auto-trades (or approval requests)
+func (tb *TelegramBot) tradeForEligibleUsers(listings []ListingInfo, users []*UserData) {
+	// Resolve every ticker to its Bitget contract once; unmapped tickers are explained, not traded
+	bitgetLaunch := make(map[string]time.Time)
+	unmapped := make(map[string]string)
+	for i, listing := range listings {
+		if listing.Canary {
+			listings[i].TradingSymbol = listing.Symbol + "USDT" // No Bitget contract; never ordered
+			continue
+		}
+		mapping, err := symbolMapper.Resolve(listing)
+		if err != nil {
+			log.Printf("🗺️ %s not traded: %v", listing.Symbol, err)
+			unmapped[listing.Symbol] = err.Error()
+			continue
+		}
+		if mapping.Note != "" {
+			log.Printf("🗺️ %s mapped to %s (%s): %s", listing.Symbol, mapping.Symbol, mapping.Source, mapping.Note)
+		}
+		listings[i].TradingSymbol = mapping.Symbol
+		if !mapping.Verified {
+			listings[i].Unverified = "proje adı doğrulanmadı"
+		}
+		bitgetLaunch[listing.Symbol] = mapping.Contract.LaunchedAt()
+	}
+
+	plans := make(map[int64][]TradeAllocation)
+	claims := make(map[string][]*UserData) // Symbol -> users with margin planned on it
+	bySymbol := make(map[string]ListingInfo)
+	for _, listing := range listings {
+		bySymbol[listing.Symbol] = listing
+	}
+	for _, user := range users {
+		if user.IsPaused {
+			log.Printf("⏸️ User %d paused auto-trading, skipping %d tickers", user.UserID, len(listings))
+			continue
+		}
+
+		var tradable []ListingInfo
+		for _, listing := range listings {
+			if tb.dispatcher.Handled(listing, user.UserID) {
+				continue // Already traded from another source
+			}
+			if reason, ok := unmapped[listing.Symbol]; ok {
+				tb.sendMessage(user.UserID, fmt.Sprintf("⏭️ %s atlandı: %s", listing.Symbol, reason))
+				continue
+			}
+			if reason := user.listingSkipReason(listing, bitgetLaunch[listing.Symbol]); reason != "" {
+				log.Printf("⏭️ User %d filtered out %s: %s", user.UserID, listing.Symbol, reason)
+				if !listing.Canary {
+					tb.sendMessage(user.UserID, fmt.Sprintf("⏭️ %s atlandı (filtre): %s", listing.Symbol, reason))
+				}
+				continue
+			}
+			tradable = append(tradable, listing)
+		}
+		if len(tradable) == 0 {
+			continue
+		}
+
+		plan := user.allocationPlan(tradable)
+		plans[user.UserID] = plan
+		for _, allocation := range plan {
+			if allocation.MarginUSDT > 0 {
+				claims[allocation.Listing.Symbol] = append(claims[allocation.Listing.Symbol], user)
+			}
+		}
+	}
+
+	// Claimed in the ledger before anything starts, so no source or restart trades a user twice
+	claimed := make(map[string]map[int64]bool)
+	for symbol, symbolUsers := range claims {
+		claimed[symbol] = make(map[int64]bool)
+		for _, user := range tb.dispatcher.ClaimUsers(bySymbol[symbol], symbolUsers) {
+			claimed[symbol][user.UserID] = true
+		}
+	}
+
+	started := 0
+	for _, user := range users {
+		plan, ok := plans[user.UserID]
+		if !ok {
+			continue
+		}
+		canary := false
+		for i, allocation := range plan {
+			if !claimed[allocation.Listing.Symbol][user.UserID] {
+				plan[i].MarginUSDT = 0
+				continue
+			}
+			started++
+			// The canary stops here, where the order or approval request would start
+			if allocation.Listing.Canary {
+				canary = true
+				continue
+			}
+			if user.ManualConfirm {
+				go tb.requestTradeApproval(user, allocation.Listing, allocation.MarginUSDT)
+				continue
+			}
+			tb.executor.Submit(user, allocation.Listing, allocation.MarginUSDT)
+		}
+		if canary {
+			continue
+		}
+		// Trigger message with the plan, sent after the jobs are queued
+		go tb.sendTradePlan(user, plan)
+	}
+
+	if len(listings) > 0 && listings[0].Canary {
+		log.Printf("🐤 Canary reached order placement: %d trades for %d users skipped", started, len(plans))
+		return
+	}
+	log.Printf("📊 %d trades started for %d users on %d tickers", started, len(plans), len(listings))
+}
+
+// sendTradePlan tells the user which tickers of a notice get which margin
+func (tb *TelegramBot) sendTradePlan(user *UserData, plan []TradeAllocation) {
+	var symbols []string
+	for _, allocation := range plan {
+		if allocation.MarginUSDT > 0 {
+			symbols = append(symbols, allocation.Listing.TradingSymbol)
+		}
+	}
+	if len(symbols) == 0 {
+		return
+	}
+	if user.ManualConfirm && len(plan) == 1 {
+		return // The approval message already shows the single ticker's margin
+	}
+
+	action := "Long pozisyonlar açılıyor..."
+	if user.ManualConfirm {
+		action = "Her coin için onayınız bekleniyor..."
+	}
+	tb.sendMessage(user.UserID, fmt.Sprintf("🚀 Otomatik işlem tetiklendi: %s\n\n%s\n\n%s%s",
+		strings.Join(symbols, ", "), formatAllocationPlan(user, plan), action, formatNoticeDetails(plan[0].Listing)))
+}
+
+// handlePauseToggle pauses or resumes auto-trading without touching the setup
+func (tb *TelegramBot) handlePauseToggle(chatID int64, userID int64, paused bool) {
+	user, exists := tb.getUser(userID)
+	if !exists || !user.IsActive {
+		tb.sendMessage(chatID, "❌ Setup'ınız tamamlanmamış. 🔧 Setup butonuna tıklayın.")
+		return
+	}
+
+	user.IsPaused = paused
+	if err := tb.saveUser(user); err != nil {
+		tb.sendMessage(chatID, fmt.Sprintf("❌ Ayar kaydedilemedi: %v", err))
+		return
+	}
+
+	text := "▶️ Otomatik işlem tekrar aktif. Yeni listelemelerde pozisyon açılacak."
+	if paused {
+		text = "⏸️ Otomatik işlem duraklatıldı. Ayarlarınız korunuyor, ▶️ Devam Et ile geri açabilirsiniz."
+	}
+	log.Printf("⏯️ User %d set paused=%v", userID, paused)
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = tb.createMainMenu()
+	tb.bot.Send(msg)
+}
+
+// handleFiltersQuery shows the user's listing filters and how to change them
+func (tb *TelegramBot) handleFiltersQuery(chatID int64, userID int64) {
+	user, exists := tb.getUser(userID)
+	if !exists {
+		tb.sendMessage(chatID, "❌ Henüz hiç kurulum yapmadınız. 🔧 Setup butonuna tıklayın.")
+		return
+	}
+
+	orAll := func(list []string) string {
+		if len(list) == 0 {
+			return "hepsi"
+		}
+		return strings.Join(list, ", ")
+	}
+	orNone := func(list []string) string {
+		if len(list) == 0 {
+			return "yok"
+		}
+		return strings.Join(list, ", ")
+	}
+
+	source := user.ListingSource
+	if source == "" {
+		source = "hepsi"
+	}
+	maxAge := "kapalı"
+	if user.MaxBitgetAgeDays > 0 {
+		maxAge = fmt.Sprintf("%d gün", user.MaxBitgetAgeDays)
+	}
+
+	filtersMsg := fmt.Sprintf(`🎛️ LİSTELEME FİLTRELERİ
+
+• Durum: %s
+• İzin listesi: %s
+• Engel listesi: %s
+• Kaynak: %s
+• Sadece KRW marketi: %s
+• Bitget'te eski listeleri atla: %s
+• Çoklu coin duyurusu: %s
+• Giriş emri: %s
+• Delist/uyarı duyurusu: %s
+
+✋ MANUEL ONAY:
+• Durum: %s
+• Onay süresi: %s
+• Hazır marjinler: %s
+
+✏️ DEĞİŞTİRMEK İÇİN:
+/allow BTC,ETH - sadece bu coinler (/allow off = hepsi)
+/deny XRP - bu coinleri atla (/deny off = temizle)
+/source upbit|file|all - listeleme kaynağı
+/krwonly on|off - sadece KRW marketi
+/maxage 30 - Bitget'te 30 günden eski ise atla (0 = kapalı)
+/allocation split|full|first|cap 150 - tek duyuruda birden çok coin varsa marjin dağılımı
+/entry market | ioc 1.5 2 | fok 1.5 - giriş emri: IOC/FOK limit, en fazla %%1.5 kayma, 2 yeniden deneme
+/entry twap 5 3 2 - 5 parçada 3 saniyeye yay, fiyat %%2'yi aşarsa dur
+/negative close|short 50 5|off - delist/uyarı duyurusunda long kapat (short: ayrıca 50 USDT x5 short aç, varsayılan: off)
+/pause - /resume - otomatik işlemi duraklat/devam ettir
+/confirm on|off - işlemden önce Al/Atla onayı iste
+/confirmtimeout 60 - onay süresi (saniye)
+/presets 50,100,200 - onay ekranındaki hazır marjinler (/presets off = kapalı)`,
+		map[bool]string{true: "⏸️ Duraklatıldı", false: "▶️ Aktif"}[user.IsPaused],
+		orAll(user.AllowSymbols),
+		orNone(user.DenySymbols),
+		source,
+		map[bool]string{true: "Evet", false: "Hayır"}[user.KRWOnly],
+		maxAge,
+		user.formatAllocationPolicy(),
+		user.formatEntryMode(),
+		user.formatNegativeAction(),
+		map[bool]string{true: "✋ Açık", false: "Kapalı (anında işlem)"}[user.ManualConfirm],
+		user.confirmTimeout(),
+		formatMarginPresets(user.MarginPresets))
+
+	msg := tgbotapi.NewMessage(chatID, filtersMsg)
+	msg.ReplyMarkup = tb.createMainMenu()
+	tb.bot.Send(msg)
+}
+
+// handleFilterCommand updates one listing filter from a /allow, /deny, /source, /krwonly, /maxage, /allocation, /entry or /negative command
+func (tb *TelegramBot) handleFilterCommand(chatID int64, userID int64, command string, args string) {
+	user, exists := tb.getUser(userID)
+	if !exists {
+		tb.sendMessage(chatID, "❌ Henüz hiç kurulum yapmadınız. 🔧 Setup butonuna tıklayın.")
+		return
+	}
+
+	args = strings.TrimSpace(args)
+
+	switch command {
+	case "allow":
+		user.AllowSymbols = parseSymbolList(args)
+	case "deny":
+		user.DenySymbols = parseSymbolList(args)
+	case "source":
+		switch strings.ToLower(args) {
+		case "", "all":
+			user.ListingSource = ""
+		case string(ListingSourceUpbit), string(ListingSourceFile):
+			user.ListingSource = strings.ToLower(args)
+		default:
+			tb.sendMessage(chatID, "❌ Geçersiz kaynak! Kullanım: /source upbit|file|all")
+			return
+		}
+	case "krwonly":
+		switch strings.ToLower(args) {
+		case "on":
+			user.KRWOnly = true
+		case "off":
+			user.KRWOnly = false
+		default:
+			tb.sendMessage(chatID, "❌ Kullanım: /krwonly on|off")
+			return
+		}
+	case "maxage":
+		days, err := strconv.Atoi(args)
+		if err != nil || days < 0 {
+			tb.sendMessage(chatID, "❌ Geçersiz gün sayısı! Kullanım: /maxage 30 (0 = kapalı)")
+			return
+		}
+		user.MaxBitgetAgeDays = days
+	case "allocation":
+		policy, capUSDT, err := parseAllocationArgs(args)
+		if err != nil {
+			tb.sendMessage(chatID, fmt.Sprintf("❌ %v! Kullanım: /allocation split|full|first|cap 150", err))
+			return
+		}
+		user.AllocationPolicy = policy
+		user.AllocationCapUSDT = capUSDT
+	case "entry":
+		entry, err := parseEntryArgs(args)
+		if err != nil {
+			tb.sendMessage(chatID, fmt.Sprintf("❌ %v! Kullanım: /entry market | /entry ioc 1.5 2 | /entry fok 1.5 | /entry twap 5 3 2", err))
+			return
+		}
+		user.EntryMode = entry.Mode
+		user.MaxSlippagePct = entry.MaxSlippagePct
+		user.EntryRetries = entry.Retries
+		user.TWAPSlices = entry.Slices
+		user.TWAPWindowSec = int(entry.Window / time.Second)
+	case "negative":
+		action, margin, leverage, err := parseNegativeArgs(args)
+		if err != nil {
+			tb.sendMessage(chatID, fmt.Sprintf("❌ %v! Kullanım: /negative close | /negative short 50 5 | /negative off", err))
+			return
+		}
+		user.NegativeAction = action
+		user.ShortMarginUSDT = margin
+		user.ShortLeverage = leverage
+	}
+
+	if err := tb.saveUser(user); err != nil {
+		tb.sendMessage(chatID, fmt.Sprintf("❌ Ayar kaydedilemedi: %v", err))
+		return
+	}
+
+	log.Printf("🎛️ User %d updated filter /%s %s", userID, command, args)
+	tb.handleFiltersQuery(chatID, userID)
+}
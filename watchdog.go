@@ -0,0 +1,218 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultWatchdogStale         = 30 * time.Second
+	defaultWatchdogQuarantinePct = 80
+	defaultWatchdogParseFailures = 5
+	watchdogCheckInterval        = 5 * time.Second
+)
+
+// detectionWatchdog is set by main; /health reads its thresholds
+var detectionWatchdog *Watchdog
+
+// HealthSnapshot is the detection loop's self-diagnosis
+type HealthSnapshot struct {
+	Running          bool
+	Paused           bool
+	PollMode         string
+	LastHealthyPoll  time.Time
+	LastHealthyProxy string
+	SinceHealthy     time.Duration // Since the last 200/304, or since start/resume if none yet
+	Proxies          int
+	Quarantined      int
+	Resting          int // Includes quarantined proxies and the random loop's per-proxy rest
+	ParseFailures    int
+	LastParseError   string
+	Rate             RateStats
+}
+
+// QuarantinedPct is the share of proxies the scheduler has quarantined
+func (h HealthSnapshot) QuarantinedPct() float64 {
+	if h.Proxies == 0 {
+		return 0
+	}
+	return float64(h.Quarantined) / float64(h.Proxies) * 100
+}
+
+// recordPoll notes a successful 200/304 poll
+func (um *UpbitMonitor) recordPoll(proxyID string, outcome ProxyOutcome) {
+	if !outcome.Healthy() {
+		return
+	}
+	um.healthMu.Lock()
+	um.lastHealthyPoll = time.Now()
+	um.lastHealthyProxy = proxyID
+	um.healthMu.Unlock()
+}
+
+// recordParseResult counts consecutive unusable notice payloads
+func (um *UpbitMonitor) recordParseResult(err error) {
+	um.healthMu.Lock()
+	defer um.healthMu.Unlock()
+
+	if err == nil {
+		um.parseFailures = 0
+		return
+	}
+	um.parseFailures++
+	um.lastParseError = err.Error()
+}
+
+// forgetETag lets another poll re-fetch and re-parse an ETag whose payload was unusable
+func (um *UpbitMonitor) forgetETag(proxyID, etag string) {
+	um.etagProcessMu.Lock()
+	if um.lastProcessedETag == etag {
+		um.lastProcessedETag = ""
+	}
+	um.etagProcessMu.Unlock()
+
+	um.etagMu.Lock()
+	if um.proxyETags[proxyID] == etag {
+		delete(um.proxyETags, proxyID)
+	}
+	um.etagMu.Unlock()
+}
+
+// Health returns the current detection loop diagnosis
+func (um *UpbitMonitor) Health() HealthSnapshot {
+	proxies := um.Proxies()
+	healthy := um.healthyProxyCount()
+	available := len(um.getAvailableProxies())
+
+	um.pauseMu.Lock()
+	paused := um.isPaused
+	um.pauseMu.Unlock()
+
+	um.healthMu.Lock()
+	defer um.healthMu.Unlock()
+
+	snapshot := HealthSnapshot{
+		Running:          !um.startedAt.IsZero(),
+		Paused:           paused,
+		PollMode:         um.pollMode,
+		LastHealthyPoll:  um.lastHealthyPoll,
+		LastHealthyProxy: um.proxyDisplayName(um.lastHealthyProxy),
+		Proxies:          len(proxies),
+		Quarantined:      max(0, len(proxies)-healthy),
+		Resting:          max(0, len(proxies)-available),
+		ParseFailures:    um.parseFailures,
+		LastParseError:   um.lastParseError,
+		Rate:             um.rateController.Stats(healthy),
+	}
+	if snapshot.Running {
+		// startedAt moves on resume so quiet hours don't count as an outage
+		snapshot.SinceHealthy = time.Since(um.startedAt)
+		if um.lastHealthyPoll.After(um.startedAt) {
+			snapshot.SinceHealthy = time.Since(um.lastHealthyPoll)
+		}
+	}
+	return snapshot
+}
+
+// Watchdog alerts admins when the detection loop silently stops working
+type Watchdog struct {
+	monitor       *UpbitMonitor
+	notify        func(text string)
+	stale         time.Duration // Max time without a 200/304
+	quarantinePct float64       // Max share of quarantined proxies
+	parseFailures int           // Max consecutive unusable payloads
+
+	mu       sync.Mutex
+	alerting map[string]bool // Condition -> alert sent and not yet recovered
+}
+
+// NewWatchdog reads WATCHDOG_STALE_SEC, WATCHDOG_QUARANTINE_PCT and WATCHDOG_PARSE_FAILURES
+func NewWatchdog(monitor *UpbitMonitor, notify func(text string)) *Watchdog {
+	return &Watchdog{
+		monitor:       monitor,
+		notify:        notify,
+		stale:         envDuration("WATCHDOG_STALE_SEC", time.Second, defaultWatchdogStale),
+		quarantinePct: envFloat("WATCHDOG_QUARANTINE_PCT", defaultWatchdogQuarantinePct),
+		parseFailures: int(envFloat("WATCHDOG_PARSE_FAILURES", defaultWatchdogParseFailures)),
+		alerting:      make(map[string]bool),
+	}
+}
+
+// Run checks the monitor's health every few seconds until stop is closed
+func (w *Watchdog) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(watchdogCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.check()
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (w *Watchdog) check() {
+	h := w.monitor.Health()
+	if !h.Running {
+		return
+	}
+
+	// Quiet hours are expected silence, not an outage
+	w.evaluate("stale", !h.Paused && h.SinceHealthy > w.stale,
+		fmt.Sprintf("🚨 UPBIT TESPİT DURDU\n\n%v boyunca başarılı (200/304) istek yok.\n• Karantinada: %d/%d proxy\n• Son başarılı proxy: %s\n\nListelemeler kaçırılabilir!",
+			h.SinceHealthy.Round(time.Second), h.Quarantined, h.Proxies, orDash(h.LastHealthyProxy)),
+		"✅ Upbit tespiti yeniden çalışıyor.")
+
+	w.evaluate("quarantine", h.QuarantinedPct() > w.quarantinePct,
+		fmt.Sprintf("⚠️ PROXY HAVUZU ZAYIF\n\n%d/%d proxy karantinada (%%%.0f, eşik %%%.0f).\nProxy listesini kontrol edin (/reloadproxies, /proxyreport).",
+			h.Quarantined, h.Proxies, h.QuarantinedPct(), w.quarantinePct),
+		fmt.Sprintf("✅ Proxy havuzu toparlandı: %d/%d karantinada.", h.Quarantined, h.Proxies))
+
+	w.evaluate("parse", h.ParseFailures >= w.parseFailures,
+		fmt.Sprintf("⚠️ UPBIT YANITI İŞLENEMİYOR\n\nArt arda %d yanıt ayrıştırılamadı.\nSon hata: %s\n\nUpbit API formatı değişmiş olabilir.",
+			h.ParseFailures, h.LastParseError),
+		"✅ Upbit yanıtları yeniden işleniyor.")
+
+	if pipelineCanary == nil {
+		return
+	}
+	c := pipelineCanary.Status()
+	w.evaluate("canary", c.Failed,
+		fmt.Sprintf("🚨 KANARYA TESTİ BAŞARISIZ\n\nSentetik listeleme duyurusu tespit hattından geçemedi.\nHata: %s\n\nGerçek listelemeler kaçırılabilir!", c.LastError),
+		"✅ Kanarya testi yeniden başarılı.")
+	w.evaluate("canary_latency", c.Regressed,
+		fmt.Sprintf("⚠️ KANARYA GECİKMESİ\n\nSentetik duyurunun işlenmesi %v sürdü (medyan %v).",
+			c.LastLatency.Round(time.Microsecond), c.MedianLatency.Round(time.Microsecond)),
+		fmt.Sprintf("✅ Kanarya gecikmesi normale döndü: %v", c.LastLatency.Round(time.Microsecond)))
+}
+
+// evaluate sends alert when a condition starts and recovery when it clears
+func (w *Watchdog) evaluate(condition string, failing bool, alert, recovery string) {
+	w.mu.Lock()
+	changed := failing != w.alerting[condition]
+	w.alerting[condition] = failing
+	w.mu.Unlock()
+
+	if !changed {
+		return
+	}
+	if failing {
+		log.Printf("🚨 Watchdog: %s", strings.SplitN(alert, "\n", 2)[0])
+		w.notify(alert)
+		return
+	}
+	log.Printf("✅ Watchdog: %s recovered", condition)
+	w.notify(recovery)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,406 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	json "github.com/json-iterator/go"
+)
+
+// Webhook delivery retries: 1s, 2s, 4s between attempts
+const (
+	webhookMaxAttempts  = 4
+	webhookInitialDelay = 1 * time.Second
+	webhookTimeout      = 5 * time.Second
+	webhookResolveLimit = 3 * time.Second
+)
+
+// webhookTarget is one URL an event is delivered to, signed with its own secret
+type webhookTarget struct {
+	URL    string
+	Secret string
+	UserID int64 // 0 = operator webhook from WEBHOOK_URLS
+}
+
+// WebhookDispatcher delivers bus events to operator and per-user webhook URLs
+type WebhookDispatcher struct {
+	client        *http.Client // Operator webhooks (trusted, may be internal)
+	userClient    *http.Client // User webhooks, refuses non-public addresses at dial time
+	retryDelay    time.Duration
+	globalTargets []webhookTarget
+	userTargets   func(event Event) []webhookTarget
+}
+
+// NewWebhookDispatcher loads operator webhooks from WEBHOOK_URLS / WEBHOOK_SECRET
+func NewWebhookDispatcher(userTargets func(event Event) []webhookTarget) *WebhookDispatcher {
+	secret := os.Getenv("WEBHOOK_SECRET")
+
+	var targets []webhookTarget
+	for _, rawURL := range strings.Split(os.Getenv("WEBHOOK_URLS"), ",") {
+		rawURL = strings.TrimSpace(rawURL)
+		if rawURL == "" {
+			continue
+		}
+		if err := validateWebhookURL(rawURL); err != nil {
+			log.Printf("⚠️ Skipping webhook %s: %v", rawURL, err)
+			continue
+		}
+		targets = append(targets, webhookTarget{URL: rawURL, Secret: secret})
+	}
+
+	if len(targets) > 0 {
+		log.Printf("🪝 Loaded %d operator webhooks", len(targets))
+	}
+
+	return &WebhookDispatcher{
+		client:        &http.Client{Timeout: webhookTimeout},
+		userClient:    newUserWebhookClient(),
+		retryDelay:    webhookInitialDelay,
+		globalTargets: targets,
+		userTargets:   userTargets,
+	}
+}
+
+// errWebhookBlocked marks a user webhook whose address resolved to a non-public IP
+var errWebhookBlocked = errors.New("not a public address")
+
+// newUserWebhookClient dials only public addresses; the check runs on the resolved IP so a
+// hostname re-pointed to an internal address after /webhook accepted it is still refused
+func newUserWebhookClient() *http.Client {
+	dialer := &net.Dialer{Timeout: webhookTimeout, Control: webhookDialControl}
+	return &http.Client{
+		Timeout: webhookTimeout,
+		Transport: &http.Transport{
+			Proxy:               nil, // A proxy would make the dial check meaningless
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: webhookTimeout,
+			MaxIdleConnsPerHost: 2,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// webhookDialControl rejects connections to non-public addresses
+func webhookDialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !isPublicWebhookIP(ip) {
+		return fmt.Errorf("webhook target %s: %w", host, errWebhookBlocked)
+	}
+	return nil
+}
+
+// isPublicWebhookIP is false for loopback, private, link-local (incl. cloud metadata),
+// CGNAT, multicast and unspecified addresses
+func isPublicWebhookIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		if ip4[0] == 0 || (ip4[0] == 100 && ip4[1]&0xc0 == 64) { // 0.0.0.0/8, 100.64.0.0/10
+			return false
+		}
+	}
+	return true
+}
+
+// Start subscribes the dispatcher to every event on the bus
+func (wd *WebhookDispatcher) Start(bus *EventBus) {
+	bus.Subscribe("webhooks", wd.handleEvent)
+}
+
+func (wd *WebhookDispatcher) handleEvent(event Event) {
+	var targets []webhookTarget
+	if event.Type != EventWebhookTest { // A user's test only goes to that user's webhook
+		targets = append(targets, wd.globalTargets...)
+	}
+	if wd.userTargets != nil {
+		targets = append(targets, wd.userTargets(event)...)
+	}
+	if len(targets) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("❌ Webhook: failed to marshal %s event: %v", event.Type, err)
+		return
+	}
+
+	for _, target := range targets {
+		go wd.deliver(target, event, body)
+	}
+}
+
+// deliver POSTs the event, retrying network errors, 429 and 5xx with exponential backoff
+func (wd *WebhookDispatcher) deliver(target webhookTarget, event Event, body []byte) {
+	delay := wd.retryDelay
+
+	for attempt := 1; attempt <= webhookMaxAttempts; attempt++ {
+		retryable, err := wd.post(target, event, body)
+		if err == nil {
+			return
+		}
+
+		if !retryable || attempt == webhookMaxAttempts {
+			log.Printf("❌ Webhook %s (%s) gave up after %d attempts: %v", redactURL(target.URL), event.Type, attempt, err)
+			return
+		}
+
+		log.Printf("⚠️ Webhook %s (%s) attempt %d failed: %v - retrying in %v", redactURL(target.URL), event.Type, attempt, err, delay)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// post sends one signed delivery and reports whether a failure is worth retrying
+func (wd *WebhookDispatcher) post(target webhookTarget, event Event, body []byte) (bool, error) {
+	req, err := http.NewRequest("POST", target.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "upbit-bitget-bot-webhook/1.0")
+	req.Header.Set("X-Webhook-ID", event.ID)
+	req.Header.Set("X-Webhook-Event", string(event.Type))
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	if target.Secret != "" {
+		req.Header.Set("X-Webhook-Signature", "sha256="+signWebhook(target.Secret, timestamp, body))
+	}
+
+	client := wd.client
+	if target.UserID != 0 {
+		client = wd.userClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return !errors.Is(err, errWebhookBlocked), fmt.Errorf("request failed: %w", err)
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("status %d", resp.StatusCode)
+	default:
+		return false, fmt.Errorf("status %d", resp.StatusCode)
+	}
+}
+
+// signWebhook returns hex HMAC-SHA256 over "<timestamp>.<body>".
+// Receivers recompute it with their secret and reject stale timestamps.
+func signWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// newWebhookSecret generates a per-user signing secret
+func newWebhookSecret() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// validateWebhookURL accepts absolute http(s) URLs only
+func validateWebhookURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return fmt.Errorf("scheme must be http or https")
+	}
+	if parsed.Host == "" {
+		return fmt.Errorf("missing host")
+	}
+	return nil
+}
+
+// validateUserWebhookURL additionally requires every address the host resolves to be public
+func validateUserWebhookURL(rawURL string) error {
+	if err := validateWebhookURL(rawURL); err != nil {
+		return err
+	}
+	parsed, _ := url.Parse(rawURL)
+	host := parsed.Hostname()
+
+	if ip := net.ParseIP(host); ip != nil {
+		if !isPublicWebhookIP(ip) {
+			return fmt.Errorf("iç ağ adresi kullanılamaz (%s)", host)
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), webhookResolveLimit)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("adres çözümlenemedi: %w", err)
+	}
+	for _, addr := range addrs {
+		if !isPublicWebhookIP(addr.IP) {
+			return fmt.Errorf("%s iç ağ adresine çözümleniyor (%s)", host, addr.IP)
+		}
+	}
+	return nil
+}
+
+// encryptLegacyWebhookSecrets encrypts webhook secrets stored in plaintext before they were
+// encrypted like the Bitget credentials
+func (tb *TelegramBot) encryptLegacyWebhookSecrets() {
+	tb.database.mutex.Lock()
+	defer tb.database.mutex.Unlock()
+
+	migrated := 0
+	for _, user := range tb.database.Users {
+		if user.WebhookSecret == "" {
+			continue
+		}
+		if _, err := tb.decryptSensitiveData(user.WebhookSecret); err == nil {
+			continue
+		}
+		encrypted, err := tb.encryptSensitiveData(user.WebhookSecret)
+		if err != nil {
+			log.Printf("Warning: Failed to encrypt webhook secret for user %d: %v", user.UserID, err)
+			continue
+		}
+		user.WebhookSecret = encrypted
+		migrated++
+	}
+	if migrated == 0 {
+		return
+	}
+	if err := tb.saveDatabaseUnsafe(); err != nil {
+		log.Printf("Warning: Failed to save encrypted webhook secrets: %v", err)
+		return
+	}
+	log.Printf("🔐 Encrypted %d plaintext webhook secrets", migrated)
+}
+
+// redactURL strips credentials and query strings before logging a URL
+func redactURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "<invalid url>"
+	}
+	return parsed.Scheme + "://" + parsed.Host + parsed.Path
+}
+
+// webhookTargetsFor returns per-user webhooks interested in the event.
+// Global listing events go to every user webhook; user events only to their owner.
+func (tb *TelegramBot) webhookTargetsFor(event Event) []webhookTarget {
+	if event.Type == EventProxyCooldown {
+		return nil // Operational noise, operator webhooks only
+	}
+
+	tb.database.mutex.RLock()
+	defer tb.database.mutex.RUnlock()
+
+	var targets []webhookTarget
+	for _, user := range tb.database.Users {
+		if user.WebhookURL == "" {
+			continue
+		}
+		if event.UserID != 0 && event.UserID != user.UserID {
+			continue
+		}
+		secret, err := tb.decryptSensitiveData(user.WebhookSecret)
+		if err != nil {
+			log.Printf("Warning: Failed to decrypt webhook secret for user %d: %v", user.UserID, err)
+			continue
+		}
+		targets = append(targets, webhookTarget{URL: user.WebhookURL, Secret: secret, UserID: user.UserID})
+	}
+	return targets
+}
+
+// handleWebhookCommand manages the user's webhook: "/webhook <url>", "/webhook off", "/webhook test"
+func (tb *TelegramBot) handleWebhookCommand(chatID int64, userID int64, args string) {
+	user, exists := tb.getUser(userID)
+	if !exists {
+		tb.sendMessage(chatID, "❌ Önce /start yazın.")
+		return
+	}
+
+	args = strings.TrimSpace(args)
+	switch {
+	case args == "":
+		if user.WebhookURL == "" {
+			tb.sendMessage(chatID, `🪝 Webhook tanımlı değil.
+
+/webhook https://ornek.com/hook - listeleme ve işlem olaylarını bu adrese POST et
+/webhook test - test olayı gönder
+/webhook off - webhook'u kaldır
+
+İstekler X-Webhook-Signature başlığında sha256=HMAC(secret, "<timestamp>.<body>") ile imzalanır.`)
+			return
+		}
+		tb.sendMessage(chatID, fmt.Sprintf("🪝 Webhook: %s\n\n/webhook test - test olayı gönder\n/webhook off - kaldır", user.WebhookURL))
+		return
+
+	case strings.EqualFold(args, "off"):
+		user.WebhookURL, user.WebhookSecret = "", ""
+
+	case strings.EqualFold(args, "test"):
+		if user.WebhookURL == "" {
+			tb.sendMessage(chatID, "❌ Önce /webhook <url> ile adres tanımlayın.")
+			return
+		}
+		eventBus.Publish(EventWebhookTest, userID, map[string]string{"message": "webhook test"})
+		tb.sendMessage(chatID, "📤 Test olayı gönderildi.")
+		return
+
+	default:
+		if err := validateUserWebhookURL(args); err != nil {
+			tb.sendMessage(chatID, fmt.Sprintf("❌ Geçersiz URL: %v", err))
+			return
+		}
+		secret, err := newWebhookSecret()
+		if err != nil {
+			tb.sendMessage(chatID, fmt.Sprintf("❌ %v", err))
+			return
+		}
+		user.WebhookURL, user.WebhookSecret = args, secret
+	}
+
+	if err := tb.saveUser(user); err != nil {
+		tb.sendMessage(chatID, fmt.Sprintf("❌ Ayar kaydedilemedi: %v", err))
+		return
+	}
+
+	if user.WebhookURL == "" {
+		tb.sendMessage(chatID, "🗑️ Webhook kaldırıldı.")
+		return
+	}
+	log.Printf("🪝 User %d set webhook %s", userID, redactURL(user.WebhookURL))
+	tb.sendMessage(chatID, fmt.Sprintf("✅ Webhook kaydedildi: %s\n\n🔑 İmza anahtarı (saklayın): %s", user.WebhookURL, user.WebhookSecret))
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordedDelivery is one request received by the test webhook server
+type recordedDelivery struct {
+	at        time.Time
+	header    http.Header
+	body      []byte
+	timestamp string
+}
+
+type webhookRecorder struct {
+	mu       sync.Mutex
+	requests []recordedDelivery
+	statuses []int // Status per attempt; 200 once exhausted
+}
+
+func (wr *webhookRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	wr.mu.Lock()
+	attempt := len(wr.requests)
+	wr.requests = append(wr.requests, recordedDelivery{
+		at:        time.Now(),
+		header:    r.Header.Clone(),
+		body:      body,
+		timestamp: r.Header.Get("X-Webhook-Timestamp"),
+	})
+	status := http.StatusOK
+	if attempt < len(wr.statuses) {
+		status = wr.statuses[attempt]
+	}
+	wr.mu.Unlock()
+	w.WriteHeader(status)
+}
+
+func (wr *webhookRecorder) snapshot() []recordedDelivery {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	return append([]recordedDelivery(nil), wr.requests...)
+}
+
+func newTestDispatcher(retryDelay time.Duration) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		client:     &http.Client{Timeout: time.Second},
+		userClient: newUserWebhookClient(),
+		retryDelay: retryDelay,
+	}
+}
+
+func TestWebhookDeliverSignsRequest(t *testing.T) {
+	recorder := &webhookRecorder{}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	wd := newTestDispatcher(time.Millisecond)
+	event := Event{ID: "evt-1", Type: EventOrderPlaced, UserID: 7}
+	body := []byte(`{"id":"evt-1"}`)
+	wd.deliver(webhookTarget{URL: server.URL, Secret: "s3cret"}, event, body)
+
+	got := recorder.snapshot()
+	if len(got) != 1 {
+		t.Fatalf("expected 1 delivery, got %d", len(got))
+	}
+	req := got[0]
+	if want := "sha256=" + signWebhook("s3cret", req.timestamp, body); req.header.Get("X-Webhook-Signature") != want {
+		t.Errorf("signature = %q, want %q", req.header.Get("X-Webhook-Signature"), want)
+	}
+	if req.header.Get("X-Webhook-ID") != "evt-1" || req.header.Get("X-Webhook-Event") != string(EventOrderPlaced) {
+		t.Errorf("unexpected event headers: %v", req.header)
+	}
+	if string(req.body) != string(body) {
+		t.Errorf("body = %s, want %s", req.body, body)
+	}
+}
+
+func TestWebhookDeliverUnsignedWithoutSecret(t *testing.T) {
+	recorder := &webhookRecorder{}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	newTestDispatcher(time.Millisecond).deliver(webhookTarget{URL: server.URL}, Event{ID: "evt-2"}, []byte(`{}`))
+
+	got := recorder.snapshot()
+	if len(got) != 1 || got[0].header.Get("X-Webhook-Signature") != "" {
+		t.Fatalf("expected one unsigned delivery, got %+v", got)
+	}
+}
+
+func TestWebhookDeliverRetriesWithBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		attempts int
+	}{
+		{"recovers after 5xx and 429", []int{503, 429, 200}, 3},
+		{"gives up after max attempts", []int{500, 500, 500, 500, 500}, webhookMaxAttempts},
+		{"4xx is not retried", []int{400}, 1},
+	}
+
+	const delay = 20 * time.Millisecond
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &webhookRecorder{statuses: tt.statuses}
+			server := httptest.NewServer(recorder)
+			defer server.Close()
+
+			newTestDispatcher(delay).deliver(webhookTarget{URL: server.URL, Secret: "k"}, Event{ID: "evt"}, []byte(`{}`))
+
+			got := recorder.snapshot()
+			if len(got) != tt.attempts {
+				t.Fatalf("attempts = %d, want %d", len(got), tt.attempts)
+			}
+			want := delay
+			for i := 1; i < len(got); i++ {
+				if gap := got[i].at.Sub(got[i-1].at); gap < want {
+					t.Errorf("gap before attempt %d = %v, want >= %v", i+1, gap, want)
+				}
+				want *= 2
+			}
+		})
+	}
+}
+
+func TestWebhookTestEventSkipsOperatorTargets(t *testing.T) {
+	operator := &webhookRecorder{}
+	operatorServer := httptest.NewServer(operator)
+	defer operatorServer.Close()
+	user := &webhookRecorder{}
+	userServer := httptest.NewServer(user)
+	defer userServer.Close()
+
+	wd := newTestDispatcher(time.Millisecond)
+	wd.userClient = wd.client // The test server is on loopback
+	wd.globalTargets = []webhookTarget{{URL: operatorServer.URL}}
+	wd.userTargets = func(Event) []webhookTarget {
+		return []webhookTarget{{URL: userServer.URL, UserID: 42}}
+	}
+
+	wd.handleEvent(Event{ID: "t", Type: EventWebhookTest, UserID: 42})
+	deadline := time.Now().Add(2 * time.Second)
+	for len(user.snapshot()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	if n := len(user.snapshot()); n != 1 {
+		t.Errorf("user webhook got %d deliveries, want 1", n)
+	}
+	if n := len(operator.snapshot()); n != 0 {
+		t.Errorf("operator webhook got %d deliveries of a user test, want 0", n)
+	}
+}
+
+func TestUserWebhookClientRefusesLoopback(t *testing.T) {
+	recorder := &webhookRecorder{}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	wd := newTestDispatcher(time.Millisecond)
+	retryable, err := wd.post(webhookTarget{URL: server.URL, UserID: 42}, Event{ID: "x"}, []byte(`{}`))
+	if err == nil || retryable {
+		t.Fatalf("post to loopback user webhook: retryable=%v err=%v, want non-retryable error", retryable, err)
+	}
+	if n := len(recorder.snapshot()); n != 0 {
+		t.Errorf("loopback server received %d requests", n)
+	}
+}
+
+func TestValidateUserWebhookURL(t *testing.T) {
+	tests := []struct {
+		url string
+		ok  bool
+	}{
+		{"https://8.8.8.8/hook", true},
+		{"ftp://8.8.8.8/hook", false},
+		{"http://127.0.0.1:8080/hook", false},
+		{"http://[::1]/hook", false},
+		{"http://10.1.2.3/hook", false},
+		{"http://172.16.0.1/hook", false},
+		{"http://192.168.1.1/hook", false},
+		{"http://169.254.169.254/latest/meta-data", false},
+		{"http://100.64.0.1/hook", false},
+		{"http://0.0.0.0/hook", false},
+		{"http://[fe80::1]/hook", false},
+		{"http://[fd00::1]/hook", false},
+	}
+	for _, tt := range tests {
+		err := validateUserWebhookURL(tt.url)
+		if (err == nil) != tt.ok {
+			t.Errorf("validateUserWebhookURL(%q) = %v, want ok=%v", tt.url, err, tt.ok)
+		}
+	}
+}
+
+func TestIsPublicWebhookIP(t *testing.T) {
+	for ip, want := range map[string]bool{
+		"1.1.1.1":         true,
+		"2606:4700::1111": true,
+		"127.0.0.53":      false,
+		"169.254.169.254": false,
+		"100.127.255.255": false,
+		"100.128.0.1":     true,
+		"224.0.0.1":       false,
+	} {
+		if got := isPublicWebhookIP(net.ParseIP(ip)); got != want {
+			t.Errorf("isPublicWebhookIP(%s) = %v, want %v", ip, got, want)
+		}
+	}
+}